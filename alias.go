@@ -0,0 +1,36 @@
+package gziptemplate
+
+// Alias returns a copy of t with its tags renamed according to aliases,
+// which maps the names used in the template to those to look up instead.
+// Tags not in aliases keep their name. Defaults set with SetDefault are
+// renamed with their tags.
+//
+// This allows a template to be used with data whose keys are named
+// differently from its tags. Only the tags are copied; the precompressed
+// static segments are shared with t.
+func (t *Template) Alias(aliases map[string]string) *Template {
+	rename := func(tag string) string {
+		if name, ok := aliases[tag]; ok {
+			return name
+		}
+		return tag
+	}
+
+	nt := t.cloneSettings(rename)
+	nt.template = t.template
+	nt.texts = t.texts
+	nt.tags = append([]tag(nil), t.tags...)
+	nt.textLens = t.textLens
+	nt.textPos = t.textPos
+	nt.patch = t.patch
+
+	for i := range nt.tags {
+		tag := &nt.tags[i]
+		switch tag.op {
+		case opValue, opIf, opRange, opHas:
+			tag.name = rename(tag.name)
+		}
+	}
+
+	return nt
+}
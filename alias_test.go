@@ -0,0 +1,42 @@
+package gziptemplate
+
+import "testing"
+
+func TestAlias(t *testing.T) {
+	tpl := New("[userName] [#if admin]admin[#end] [#range posts][title] [#end][site|none]", "[", "]", BestCompression)
+	tpl.SetDefault("userName", "anon")
+
+	at := tpl.Alias(map[string]string{
+		"userName": "user_name",
+		"admin":    "is_admin",
+		"posts":    "user_posts",
+		"site":     "site_name",
+	})
+
+	s := decompressBytes(t, at.ExecuteBytes(map[string]interface{}{
+		"user_name":  "bob",
+		"is_admin":   true,
+		"user_posts": []map[string]interface{}{{"title": "a"}, {"title": "b"}},
+		"site_name":  "example",
+	}))
+	result := "bob admin a b example"
+	if string(s) != result {
+		t.Fatalf("unexpected template value %q. Expected %q", s, result)
+	}
+
+	s = decompressBytes(t, at.ExecuteBytes(nil))
+	result = "anon  none"
+	if string(s) != result {
+		t.Fatalf("unexpected template value %q. Expected %q", s, result)
+	}
+
+	s = decompressBytes(t, tpl.ExecuteBytes(map[string]interface{}{"userName": "eve"}))
+	result = "eve  none"
+	if string(s) != result {
+		t.Fatalf("unexpected template value %q. Expected %q", s, result)
+	}
+}
+
+func TestAliasKeepsSettings(t *testing.T) {
+	checkSettings(t, settingsTemplate(t).Alias(nil), "<p>a; b</p>Untitled")
+}
@@ -0,0 +1,65 @@
+package gziptemplate
+
+import "go.tmthrgd.dev/gzipbuilder"
+
+// AppendFuncTo calls f on each template tag (placeholder) occurrence and
+// appends the rendered template to b.
+//
+// This allows a template to be embedded in the middle of a larger gzip stream
+// being composed with gzipbuilder, without an intermediate buffer. The
+// template's static segments are spliced into b as is, even if b uses a
+// different compression level.
+func (t *Template) AppendFuncTo(b *gzipbuilder.Builder, f TagFunc) error {
+	t.stats.executed()
+
+	return t.execute(stream{
+		add: func(d *gzipbuilder.PrecompressedData) { b.AddPrecompressedData(d) },
+		w:   b.UncompressedWriter(),
+	}, f)
+}
+
+// AppendTo substitutes template tags (placeholders) with the corresponding
+// values from the map m and appends the result to b.
+//
+// See AppendFuncTo for details.
+func (t *Template) AppendTo(b *gzipbuilder.Builder, m map[string]interface{}) error {
+	return t.AppendFuncTo(b, t.mapTagFunc(m))
+}
+
+// ExecuteFuncAppend is like ExecuteFunc but appends the gzipped output to
+// dst and returns the extended slice.
+//
+// This allows buffers to be reused across executions. On error, the returned
+// slice holds whatever was written before the error.
+func (t *Template) ExecuteFuncAppend(dst []byte, f TagFunc) ([]byte, error) {
+	if len(t.tags) == 0 {
+		t.stats.executed()
+		if err := t.checkOutput(t.template); err != nil {
+			return dst, err
+		}
+
+		return append(dst, t.template...), nil
+	}
+
+	aw := appendWriter{dst}
+	err := t.ExecuteFunc(&aw, f)
+	return aw.b, err
+}
+
+// ExecuteAppend is like Execute but appends the gzipped output to dst and
+// returns the extended slice.
+//
+// See ExecuteFuncAppend for details.
+func (t *Template) ExecuteAppend(dst []byte, m map[string]interface{}) ([]byte, error) {
+	return t.ExecuteFuncAppend(dst, t.mapTagFunc(m))
+}
+
+// appendWriter appends writes to b.
+type appendWriter struct {
+	b []byte
+}
+
+func (aw *appendWriter) Write(p []byte) (int, error) {
+	aw.b = append(aw.b, p...)
+	return len(p), nil
+}
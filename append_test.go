@@ -0,0 +1,60 @@
+package gziptemplate
+
+import (
+	"io"
+	"testing"
+
+	"go.tmthrgd.dev/gzipbuilder"
+)
+
+func TestAppendTo(t *testing.T) {
+	tpl := New("<b>[foo]</b>", "[", "]", BestCompression)
+	static := New("<hr>", "[", "]", BestCompression)
+
+	b := gzipbuilder.NewBuilder(BestSpeed)
+	io.WriteString(b.UncompressedWriter(), "<html>")
+	if err := tpl.AppendTo(b, map[string]interface{}{"foo": "111"}); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if err := static.AppendTo(b, nil); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	io.WriteString(b.UncompressedWriter(), "</html>")
+
+	s := decompressBytes(t, b.BytesOrPanic())
+	result := "<html><b>111</b><hr></html>"
+	if string(s) != result {
+		t.Fatalf("unexpected template value %q. Expected %q", s, result)
+	}
+}
+
+func TestExecuteAppend(t *testing.T) {
+	tpl := New("<b>[foo]</b>", "[", "]", BestCompression)
+	static := New("<hr>", "[", "]", BestCompression)
+
+	buf := make([]byte, 0, 1024)
+	for _, foo := range []string{"111", "222"} {
+		b, err := tpl.ExecuteAppend(buf[:0], map[string]interface{}{"foo": foo})
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if &b[0] != &buf[:1][0] {
+			t.Fatal("expected output to be appended to the provided buffer")
+		}
+
+		if s := decompressBytes(t, b); string(s) != "<b>"+foo+"</b>" {
+			t.Fatalf("unexpected template value %q. Expected %q", s, "<b>"+foo+"</b>")
+		}
+	}
+
+	b, err := static.ExecuteAppend([]byte("prefix"), nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if string(b[:6]) != "prefix" {
+		t.Fatalf("unexpected prefix %q", b[:6])
+	}
+	if s := decompressBytes(t, b[6:]); string(s) != "<hr>" {
+		t.Fatalf("unexpected template value %q. Expected %q", s, "<hr>")
+	}
+}
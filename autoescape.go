@@ -0,0 +1,448 @@
+package gziptemplate
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// htmlState is the state of an htmlContext.
+type htmlState uint8
+
+const (
+	// stateText is HTML text.
+	stateText htmlState = iota
+
+	// stateTagName is the name of an element after '<'.
+	stateTagName
+
+	// stateTag is within a start tag, between attributes.
+	stateTag
+
+	// stateAttrName is the name of an attribute and stateAfterName the
+	// space after it.
+	stateAttrName
+	stateAfterName
+
+	// stateBeforeValue follows the '=' of an attribute and stateValue is
+	// its value.
+	stateBeforeValue
+	stateValue
+
+	// stateEndTag is within an end tag.
+	stateEndTag
+
+	// stateMarkup follows "<!", stateDeclaration is within a declaration,
+	// such as a doctype, and stateComment is within an HTML comment.
+	stateMarkup
+	stateDeclaration
+	stateComment
+
+	// stateScript is the content of a script element and stateStyle that
+	// of a style element.
+	stateScript
+	stateStyle
+)
+
+// attrKind classifies attributes by the escaping their values need.
+type attrKind uint8
+
+const (
+	attrPlain attrKind = iota
+	attrURL
+	attrJS
+	attrCSS
+)
+
+// urlAttrs are the attributes whose values are URLs.
+var urlAttrs = []string{"action", "background", "cite", "data", "formaction", "href", "poster", "src"}
+
+// htmlContext tracks the context of the end of a stream of HTML text, so
+// that a tag found there may be escaped appropriately. It is a deliberately
+// small approximation of an HTML tokenizer.
+type htmlContext struct {
+	state htmlState
+
+	// elem is the name of the element being opened and attr the name of
+	// the attribute being read.
+	elem []byte
+	attr []byte
+
+	// kind is the kind of the attribute whose value is being read, quote
+	// its quote character or zero if unquoted, empty reports whether
+	// nothing but whitespace of the value has been read and query whether
+	// a URL value has reached its query.
+	kind  attrKind
+	quote byte
+	empty bool
+	query bool
+
+	// match counts the characters of "<!--", "-->" or the end tag of a
+	// script or style element seen.
+	match int
+
+	// js tracks the JavaScript of a script element or event handler
+	// attribute.
+	js jsContext
+}
+
+// jsContext tracks the string literals and comments of JavaScript.
+type jsContext struct {
+	// quote is the quote character of the string literal being read, or
+	// zero, and escaped reports whether the last character was a
+	// backslash within it.
+	quote   byte
+	escaped bool
+
+	// comment is '/' within a line comment, '*' within a block comment or
+	// zero, and prev is the last character outside of literals.
+	comment byte
+	prev    byte
+}
+
+// write advances the context over p.
+func (c *htmlContext) write(p []byte) {
+	for _, b := range p {
+		c.byte(b)
+	}
+}
+
+func (c *htmlContext) byte(b byte) {
+	switch c.state {
+	case stateText:
+		if b == '<' {
+			c.state, c.elem = stateTagName, c.elem[:0]
+		}
+	case stateTagName:
+		switch {
+		case isLetter(b):
+			c.elem = append(c.elem, lower(b))
+		case b == '/' && len(c.elem) == 0:
+			c.state = stateEndTag
+		case b == '!' && len(c.elem) == 0:
+			c.state, c.match = stateMarkup, 0
+		case len(c.elem) == 0:
+			c.state = stateText
+		case b == '>':
+			c.open()
+		default:
+			c.state = stateTag
+		}
+	case stateTag:
+		switch {
+		case b == '>':
+			c.open()
+		case isSpace(b), b == '/':
+		default:
+			c.state, c.attr = stateAttrName, append(c.attr[:0], lower(b))
+		}
+	case stateAttrName, stateAfterName:
+		switch {
+		case b == '=':
+			c.state = stateBeforeValue
+			c.kind, c.quote, c.empty, c.query = classifyAttr(string(c.attr)), 0, true, false
+			c.js = jsContext{}
+		case b == '>':
+			c.open()
+		case isSpace(b):
+			c.state = stateAfterName
+		case c.state == stateAfterName:
+			c.state, c.attr = stateAttrName, append(c.attr[:0], lower(b))
+		default:
+			c.attr = append(c.attr, lower(b))
+		}
+	case stateBeforeValue:
+		switch {
+		case isSpace(b):
+		case b == '"' || b == '\'':
+			c.state, c.quote = stateValue, b
+		case b == '>':
+			c.open()
+		default:
+			c.state = stateValue
+			c.value(b)
+		}
+	case stateValue:
+		switch {
+		case c.quote != 0 && b == c.quote:
+			c.state = stateTag
+		case c.quote == 0 && isSpace(b):
+			c.state = stateTag
+		case c.quote == 0 && b == '>':
+			c.open()
+		default:
+			c.value(b)
+		}
+	case stateEndTag:
+		if b == '>' {
+			c.state = stateText
+		}
+	case stateMarkup:
+		switch {
+		case b == '-' && c.match == 0:
+			c.match = 1
+		case b == '-':
+			c.state, c.match = stateComment, 0
+		case b == '>':
+			c.state = stateText
+		default:
+			c.state = stateDeclaration
+		}
+	case stateDeclaration:
+		if b == '>' {
+			c.state = stateText
+		}
+	case stateComment:
+		switch {
+		case b == '>' && c.match >= 2:
+			c.state = stateText
+		case b == '-':
+			c.match++
+		default:
+			c.match = 0
+		}
+	case stateScript, stateStyle:
+		// The element ends at its end tag, c.elem being its name.
+		switch {
+		case c.match == 0 && b == '<', c.match == 1 && b == '/':
+			c.match++
+		case c.match >= 2 && lower(b) == c.elem[c.match-2]:
+			if c.match++; c.match == len(c.elem)+2 {
+				c.state = stateEndTag
+				return
+			}
+		case b == '<':
+			c.match = 1
+		default:
+			c.match = 0
+		}
+
+		if c.state == stateScript {
+			c.js.byte(b)
+		}
+	}
+}
+
+// open completes the start tag of the element c.elem.
+func (c *htmlContext) open() {
+	switch string(c.elem) {
+	case "script":
+		c.state, c.match, c.js = stateScript, 0, jsContext{}
+	case "style":
+		c.state, c.match = stateStyle, 0
+	default:
+		c.state = stateText
+	}
+}
+
+// value advances over b in an attribute value.
+func (c *htmlContext) value(b byte) {
+	// Browsers ignore whitespace at the start of a URL.
+	if !isSpace(b) {
+		c.empty = false
+	}
+
+	switch c.kind {
+	case attrURL:
+		if b == '?' || b == '#' {
+			c.query = true
+		}
+	case attrJS:
+		c.js.byte(b)
+	}
+}
+
+// byte advances the context over the JavaScript character b.
+func (js *jsContext) byte(b byte) {
+	switch {
+	case js.comment == '/':
+		if b == '\n' {
+			js.comment = 0
+		}
+	case js.comment == '*':
+		if js.prev == '*' && b == '/' {
+			js.comment, b = 0, 0
+		}
+		js.prev = b
+	case js.quote != 0:
+		switch {
+		case js.escaped:
+			js.escaped = false
+		case b == '\\':
+			js.escaped = true
+		case b == js.quote:
+			js.quote = 0
+		}
+	case b == '"' || b == '\'' || b == '`':
+		js.quote = b
+	case js.prev == '/' && (b == '/' || b == '*'):
+		js.comment, js.prev = b, 0
+	default:
+		js.prev = b
+	}
+}
+
+// escape returns how a value is to be escaped at the end of the text written
+// so far: the escapeFunc to apply and the text, if any, to write either side
+// of it. It returns an error if values may not be substituted there.
+func (c *htmlContext) escape() (escapeFunc, string, error) {
+	switch c.state {
+	case stateText:
+		return escapeHTML, "", nil
+	case stateScript:
+		return c.js.escape(nil, `"`)
+	case stateBeforeValue, stateValue:
+		attr := escapeHTML
+		if c.state == stateBeforeValue || c.quote == 0 {
+			attr = escapeUnquotedAttr
+		}
+
+		switch {
+		case c.kind == attrJS:
+			return c.js.escape(attr, "&#34;")
+		case c.kind == attrCSS:
+			return nil, "", fmt.Errorf("gziptemplate: cannot substitute values within a style attribute")
+		case c.kind == attrURL && c.query:
+			return escapeURL, "", nil
+		default:
+			return attr, "", nil
+		}
+	default:
+		return nil, "", fmt.Errorf("gziptemplate: cannot substitute values within %s", c.describe())
+	}
+}
+
+// filtersURL reports whether values at the end of the text written so far
+// begin a URL, and so must be checked for unsafe schemes with newURLFilter.
+func (c *htmlContext) filtersURL() bool {
+	return (c.state == stateBeforeValue || c.state == stateValue) && c.kind == attrURL && c.empty
+}
+
+// escape returns the escaping for a value at the end of the JavaScript so
+// far, followed by then. Values outside of string literals are written as
+// string literals quoted by quote.
+func (js *jsContext) escape(then escapeFunc, quote string) (escapeFunc, string, error) {
+	switch {
+	case js.comment != 0:
+		return nil, "", fmt.Errorf("gziptemplate: cannot substitute values within a JavaScript comment")
+	case js.quote == '`':
+		return nil, "", fmt.Errorf("gziptemplate: cannot substitute values within a JavaScript template literal")
+	case js.quote != 0:
+		return chainEscape(escapeJS, then), "", nil
+	default:
+		return chainEscape(escapeJS, then), quote, nil
+	}
+}
+
+// describe names the context for error messages.
+func (c *htmlContext) describe() string {
+	switch c.state {
+	case stateTagName:
+		return "an element name"
+	case stateEndTag:
+		return "an end tag"
+	case stateMarkup, stateDeclaration:
+		return "a declaration"
+	case stateComment:
+		return "an HTML comment"
+	case stateStyle:
+		return "a style element"
+	default:
+		return "an element outside of attribute values"
+	}
+}
+
+// unquotedAttrReplacer escapes the characters that would end, or otherwise
+// change the meaning of, an unquoted attribute value.
+var unquotedAttrReplacer = strings.NewReplacer(
+	"&", "&amp;",
+	"<", "&lt;",
+	">", "&gt;",
+	`"`, "&#34;",
+	"'", "&#39;",
+	"`", "&#96;",
+	"=", "&#61;",
+	" ", "&#32;",
+	"\t", "&#9;",
+	"\n", "&#10;",
+	"\f", "&#12;",
+	"\r", "&#13;",
+	"\x00", "\uFFFD",
+)
+
+func escapeUnquotedAttr(w io.Writer, p []byte) error {
+	_, err := unquotedAttrReplacer.WriteString(w, string(p))
+	return err
+}
+
+// newURLFilter returns an escapeFunc that passes a URL through unless it
+// has a scheme other than http, https or mailto, as html/template does, in
+// which case the rest of the URL, from the colon on, is replaced by
+// "#ZgotmplZ". It keeps state across writes, so each value needs its own.
+func newURLFilter() escapeFunc {
+	var (
+		// scheme holds the lower-cased start of the URL, up to one byte
+		// longer than the longest safe scheme.
+		scheme  []byte
+		decided bool
+		blocked bool
+	)
+	return func(w io.Writer, p []byte) error {
+		if blocked {
+			return nil
+		}
+
+		for i := 0; i < len(p) && !decided; i++ {
+			switch c := p[i]; c {
+			case '/':
+				decided = true
+			case ':':
+				decided = true
+				switch string(scheme) {
+				case "http", "https", "mailto":
+				default:
+					blocked = true
+					if _, err := w.Write(p[:i]); err != nil {
+						return err
+					}
+
+					_, err := io.WriteString(w, "#ZgotmplZ")
+					return err
+				}
+			default:
+				if len(scheme) == 0 && isSpace(c) {
+					continue
+				}
+				if len(scheme) <= len("mailto") {
+					scheme = append(scheme, lower(c))
+				}
+			}
+		}
+
+		_, err := w.Write(p)
+		return err
+	}
+}
+
+// classifyAttr returns the kind of the attribute name, which must be lower
+// case.
+func classifyAttr(name string) attrKind {
+	if strings.HasPrefix(name, "on") {
+		return attrJS
+	}
+	if name == "style" {
+		return attrCSS
+	}
+
+	for _, attr := range urlAttrs {
+		if name == attr {
+			return attrURL
+		}
+	}
+
+	return attrPlain
+}
+
+func isLetter(b byte) bool {
+	return 'a' <= lower(b) && lower(b) <= 'z'
+}
@@ -0,0 +1,121 @@
+package gziptemplate
+
+import (
+	"io"
+	"testing"
+)
+
+func TestWithAutoEscape(t *testing.T) {
+	for _, test := range []struct {
+		template string
+		result   string
+	}{
+		{"<p>[v]</p>", "<p>&lt;a href=&#34;x?y=1&amp;z&#34;&gt;&#39;</p>"},
+		{`<p title="[v]">`, `<p title="&lt;a href=&#34;x?y=1&amp;z&#34;&gt;&#39;">`},
+		{`<p title='x' class=[v]>`, `<p title='x' class=&lt;a&#32;href&#61;&#34;x?y&#61;1&amp;z&#34;&gt;&#39;>`},
+		{`<a href="/search?q=[v]">`, `<a href="/search?q=%3Ca+href%3D%22x%3Fy%3D1%26z%22%3E%27">`},
+		{`<a href="[v]">`, `<a href="&lt;a href=&#34;x?y=1&amp;z&#34;&gt;&#39;">`},
+		{`<button onclick="f('[v]')">`, `<button onclick="f('\u003Ca href\u003D\&#34;x?y\u003D1\u0026z\&#34;\u003E\&#39;')">`},
+		{`<script>var s = "[v]";</script>[v]`, `<script>var s = "\u003Ca href\u003D\"x?y\u003D1\u0026z\"\u003E\'";</script>&lt;a href=&#34;x?y=1&amp;z&#34;&gt;&#39;`},
+		{`<SCRIPT type="text/javascript">if (a < b) {}</Script ><p>[v]`, `<SCRIPT type="text/javascript">if (a < b) {}</Script ><p>&lt;a href=&#34;x?y=1&amp;z&#34;&gt;&#39;`},
+		{`<!-- <a href="[raw]"> -->[v]`, `<!-- <a href="<br>"> -->&lt;a href=&#34;x?y=1&amp;z&#34;&gt;&#39;`},
+		{`<p>[raw]</p>`, `<p><br></p>`},
+		{`<p>[v|none]</p>`, `<p>&lt;a href=&#34;x?y=1&amp;z&#34;&gt;&#39;</p>`},
+	} {
+		tpl := New(test.template, "[", "]", BestCompression, WithAutoEscape(), WithEscaping(map[string]Escaping{
+			"raw": EscapeNone,
+		}))
+
+		s := tpl.ExecuteBytes(map[string]interface{}{
+			"v":   `<a href="x?y=1&z">'`,
+			"raw": "<br>",
+		})
+		s = decompressBytes(t, s)
+		if string(s) != test.result {
+			t.Errorf("%q: unexpected template value %q. Expected %q", test.template, s, test.result)
+		}
+	}
+}
+
+func TestWithAutoEscapeInjection(t *testing.T) {
+	for _, test := range []struct {
+		template string
+		value    string
+		result   string
+	}{
+		{`<a title=[v]>`, `x onmouseover=alert(1)`, `<a title=x&#32;onmouseover&#61;alert(1)>`},
+		{`<a title=[v] href="/">`, "x>\t`", `<a title=x&gt;&#9;&#96; href="/">`},
+		{`<script>var n = [v];</script>`, `1;alert(1)`, `<script>var n = "1;alert(1)";</script>`},
+		{`<script>var s = '[v]'; // "x"` + "\n" + `f([v]);</script>`, `'"`, `<script>var s = '\'\"'; // "x"` + "\n" + `f("\'\"");</script>`},
+		{`<button onclick="f([v])">`, `"x"`, `<button onclick="f(&#34;\&#34;x\&#34;&#34;)">`},
+		{`<button onclick="f('[v]')">`, `");alert(1);("`, `<button onclick="f('\&#34;);alert(1);(\&#34;')">`},
+		{`<button onclick=f([v])>`, `1 onmouseover=alert(1)`, `<button onclick=f(&#34;1&#32;onmouseover\u003Dalert(1)&#34;)>`},
+		{`<!DOCTYPE html><script>var x = [v];</script>`, `1;alert(1)`, `<!DOCTYPE html><script>var x = "1;alert(1)";</script>`},
+		{`<!DOCTYPE html><a title=[v]>`, `x onmouseover=alert(1)`, `<!DOCTYPE html><a title=x&#32;onmouseover&#61;alert(1)>`},
+		{`<!----><p title=[v]>`, `x onmouseover=alert(1)`, `<!----><p title=x&#32;onmouseover&#61;alert(1)>`},
+		{`<a href="[v]">`, `javascript:alert(1)`, `<a href="javascript#ZgotmplZ">`},
+		{`<a href=[v]>`, ` JavaScript:alert(1)`, `<a href=&#32;JavaScript#ZgotmplZ>`},
+		{`<a href="[v]">`, `data:text/html,x`, `<a href="data#ZgotmplZ">`},
+		{`<a href="[v]">`, `HTTPS://example.com/?a=b:c`, `<a href="HTTPS://example.com/?a=b:c">`},
+		{`<a href="[v]">`, `mailto:a@example.com`, `<a href="mailto:a@example.com">`},
+		{`<a href="[v]">`, `/x:javascript:y`, `<a href="/x:javascript:y">`},
+		{`<a href="/[v]">`, `javascript:alert(1)`, `<a href="/javascript:alert(1)">`},
+		{`<a href=" [v]">`, `javascript:alert(1)`, `<a href=" javascript#ZgotmplZ">`},
+		{`<a href="[v]">`, ` http://example.com/`, `<a href=" http://example.com/">`},
+	} {
+		tpl := New(test.template, "[", "]", BestCompression, WithAutoEscape())
+
+		s := decompressBytes(t, tpl.ExecuteBytes(map[string]interface{}{"v": test.value}))
+		if string(s) != test.result {
+			t.Errorf("%q: unexpected template value %q. Expected %q", test.template, s, test.result)
+		}
+	}
+}
+
+func TestWithAutoEscapeURLFilter(t *testing.T) {
+	tpl := New(`<a href="[u]">[u]</a>`, "[", "]", BestCompression, WithAutoEscape())
+
+	for _, u := range []string{"javascript:alert(1)", "http://example.com/"} {
+		s := decompressBytes(t, tpl.ExecuteBytes(map[string]interface{}{
+			"u": TagFunc(func(w io.Writer, tag string) error {
+				// Write the URL a byte at a time so the scheme spans writes.
+				for i := 0; i < len(u); i++ {
+					if _, err := io.WriteString(w, u[i:i+1]); err != nil {
+						return err
+					}
+				}
+				return nil
+			}),
+		}))
+
+		href := u
+		if u[0] == 'j' {
+			href = "javascript#ZgotmplZ"
+		}
+		if expect := `<a href="` + href + `">` + u + `</a>`; string(s) != expect {
+			t.Errorf("%q: unexpected template value %q. Expected %q", u, s, expect)
+		}
+	}
+}
+
+func TestWithAutoEscapeUnsupported(t *testing.T) {
+	for _, template := range []string{
+		`<[v]>`,
+		`<p [v]>`,
+		`<p class="x" [v]=y>`,
+		`<p class [v]>`,
+		`</p[v]>`,
+		"<script>// [v]\n</script>",
+		`<script>/* [v] */</script>`,
+		"<script>`[v]`</script>",
+		`<button onclick="/* [v] */">`,
+		`<!-- [v] -->`,
+		`<!DOCTYPE [v]>`,
+		`<style>p { color: [v]; }</style>`,
+		`<p style="color: [v]">`,
+	} {
+		if _, err := NewTemplate(template, "[", "]", BestCompression, WithAutoEscape()); err == nil {
+			t.Errorf("%q: expected error", template)
+		}
+	}
+}
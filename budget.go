@@ -0,0 +1,88 @@
+package gziptemplate
+
+import (
+	"io"
+	"time"
+
+	"go.tmthrgd.dev/gzipbuilder"
+)
+
+// ExecuteFuncBudget is like ExecuteFunc but bounds the time spent
+// compressing tag values.
+//
+// Once budget has elapsed since the start of execution, all further data
+// written by f is emitted in stored (uncompressed) deflate blocks rather
+// than being compressed. The output remains a valid gzip stream, only
+// larger, so tail latency stays bounded when the CPU is under pressure.
+// Precompressed static segments are unaffected.
+//
+// The budget is soft: it is checked before every write made by f and never
+// interrupts a TagFunc.
+func (t *Template) ExecuteFuncBudget(w io.Writer, f TagFunc, budget time.Duration) error {
+	t.stats.executed()
+
+	if len(t.tags) == 0 {
+		if err := t.checkOutput(t.template); err != nil {
+			return err
+		}
+
+		_, err := w.Write(t.template)
+		return err
+	}
+
+	w, ol := t.limitOutput(w)
+	gw := gzipbuilder.NewWriter(w, t.level)
+	s := stream{
+		add: func(d *gzipbuilder.PrecompressedData) { gw.AddPrecompressedData(d) },
+		w:   gw.UncompressedWriter(),
+	}
+	s.w = &budgetWriter{
+		s:        s,
+		deadline: time.Now().Add(budget),
+	}
+	s = s.withLimit(ol)
+
+	if err := t.execute(s, f); err != nil {
+		return err
+	}
+
+	return gw.Close()
+}
+
+// ExecuteBudget is like Execute but bounds the time spent compressing tag
+// values.
+//
+// See ExecuteFuncBudget for details.
+func (t *Template) ExecuteBudget(w io.Writer, m map[string]interface{}, budget time.Duration) error {
+	return t.ExecuteFuncBudget(w, t.mapTagFunc(m), budget)
+}
+
+// budgetWriter compresses writes to s until deadline and emits stored
+// blocks thereafter.
+type budgetWriter struct {
+	s        stream
+	deadline time.Time
+
+	stored *gzipbuilder.PrecompressedWriter
+}
+
+func (bw *budgetWriter) Write(p []byte) (int, error) {
+	if bw.stored == nil {
+		if time.Now().Before(bw.deadline) {
+			return bw.s.w.Write(p)
+		}
+
+		bw.stored = gzipbuilder.NewPrecompressedWriter(NoCompression)
+	}
+
+	bw.stored.Reset()
+	bw.stored.Write(p)
+
+	d, err := bw.stored.Data()
+	if err != nil {
+		return 0, err
+	}
+
+	bw.s.add(d)
+	return len(p), nil
+}
@@ -0,0 +1,52 @@
+package gziptemplate
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExecuteBudget(t *testing.T) {
+	tpl := New("foo[foo]bar[bar]baz", "[", "]", BestCompression)
+	m := map[string]interface{}{
+		"foo": strings.Repeat("a", 1024),
+		"bar": "111",
+	}
+	result := "foo" + strings.Repeat("a", 1024) + "bar111baz"
+
+	for _, budget := range []time.Duration{0, time.Hour} {
+		var buf bytes.Buffer
+		if err := tpl.ExecuteBudget(&buf, m, budget); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+
+		s := decompressBytes(t, buf.Bytes())
+		if string(s) != result {
+			t.Fatalf("unexpected template value %q with budget %s. Expected %q", s, budget, result)
+		}
+	}
+}
+
+func TestExecuteFuncBudgetStored(t *testing.T) {
+	tpl := New("foo[foo]bar", "[", "]", BestCompression)
+
+	var buf bytes.Buffer
+	if err := tpl.ExecuteFuncBudget(&buf, func(w io.Writer, tag string) error {
+		for i := 0; i < 3; i++ {
+			if _, err := io.WriteString(w, strings.Repeat("a", 4096)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}, -time.Second); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	s := decompressBytes(t, buf.Bytes())
+	result := "foo" + strings.Repeat("a", 3*4096) + "bar"
+	if string(s) != result {
+		t.Fatal("unexpected template value")
+	}
+}
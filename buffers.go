@@ -0,0 +1,157 @@
+package gziptemplate
+
+import (
+	"bytes"
+	"compress/flate"
+	"encoding/binary"
+	"hash/crc32"
+	"net"
+)
+
+// ExecuteFuncBuffers calls f on each template tag (placeholder) occurrence
+// and returns the result as a sequence of byte slices.
+//
+// The static segments of the template are referenced rather than copied,
+// only the tag values are compressed into new slices, so no contiguous
+// buffer must be allocated and repeatedly grown. The result is suitable for
+// writing to a socket with writev using net.Buffers.WriteTo. It refers to
+// the template's precompressed output and must not be modified.
+func (t *Template) ExecuteFuncBuffers(f TagFunc) (net.Buffers, error) {
+	t.stats.executed()
+
+	if len(t.tags) == 0 {
+		if err := t.checkOutput(t.template); err != nil {
+			return nil, err
+		}
+
+		return net.Buffers{t.template}, nil
+	}
+
+	bw := &buffersWriter{
+		level: t.level,
+		limit: newOutputLimit(t.limits),
+	}
+	bw.emit([]byte{0x1f, 0x8b, 8, 0, 0, 0, 0, 0, 0, 0xff})
+
+	s := stream{
+		segment: bw.segment,
+		w:       bw,
+	}.withLimit(bw.limit)
+
+	if err := t.execute(s, f); err != nil {
+		return nil, err
+	}
+
+	if err := bw.close(); err != nil {
+		return nil, t.failed(err)
+	}
+
+	return bw.bufs, nil
+}
+
+// ExecuteBuffers substitutes template tags (placeholders) with the
+// corresponding values from the map m and returns the result as a sequence
+// of byte slices.
+//
+// See ExecuteFuncBuffers for details.
+func (t *Template) ExecuteBuffers(m map[string]interface{}) (net.Buffers, error) {
+	return t.ExecuteFuncBuffers(t.mapTagFunc(m))
+}
+
+// buffersWriter assembles a gzip stream from the raw deflate static segments
+// of templates, which are referenced, and the values written to it, which
+// are compressed between them.
+type buffersWriter struct {
+	bufs  net.Buffers
+	level int
+	limit *outputLimit
+
+	// fw compresses the values written since the last segment into buf.
+	fw      *flate.Writer
+	buf     bytes.Buffer
+	pending bool
+
+	// crc and size are the CRC-32 and length of the uncompressed output.
+	crc  uint32
+	size int64
+}
+
+func (bw *buffersWriter) Write(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+
+	if bw.fw == nil {
+		fw, err := flate.NewWriter(&bw.buf, bw.level)
+		if err != nil {
+			return 0, err
+		}
+
+		bw.fw = fw
+	}
+
+	n, err := bw.fw.Write(p)
+	bw.crc = crc32.Update(bw.crc, crc32.IEEETable, p[:n])
+	bw.size += int64(n)
+	bw.pending = true
+	return n, err
+}
+
+// segment adds the i'th static segment of t.
+func (bw *buffersWriter) segment(t *Template, i int) error {
+	segs, err := t.rawSegments()
+	if err != nil {
+		return err
+	}
+
+	if err := bw.flush(); err != nil {
+		return err
+	}
+
+	bw.crc = crc32Combine(bw.crc, segs[i].crc, t.textLens[i])
+	bw.size += t.textLens[i]
+	return bw.emit(segs[i].b)
+}
+
+// flush ends the deflate data of the values written since the last segment
+// byte-aligned, so that a segment may follow it. Each run of values is
+// compressed independently as back-references must not reach across the
+// segments.
+func (bw *buffersWriter) flush() error {
+	if !bw.pending {
+		return nil
+	}
+
+	if err := bw.fw.Flush(); err != nil {
+		return err
+	}
+
+	b := bw.buf.Bytes()
+	bw.buf = bytes.Buffer{}
+	bw.fw.Reset(&bw.buf)
+	bw.pending = false
+	return bw.emit(b)
+}
+
+// emit appends b to the output, counting it against the output limits.
+func (bw *buffersWriter) emit(b []byte) error {
+	bw.bufs = append(bw.bufs, b)
+	if bw.limit == nil {
+		return nil
+	}
+
+	bw.limit.compressed += int64(len(b))
+	return bw.limit.err()
+}
+
+// close ends the gzip stream with an empty final block and the trailer.
+func (bw *buffersWriter) close() error {
+	if err := bw.flush(); err != nil {
+		return err
+	}
+
+	trailer := []byte{0x03, 0x00, 0, 0, 0, 0, 0, 0, 0, 0}
+	binary.LittleEndian.PutUint32(trailer[2:], bw.crc)
+	binary.LittleEndian.PutUint32(trailer[6:], uint32(bw.size))
+	return bw.emit(trailer)
+}
@@ -0,0 +1,78 @@
+package gziptemplate
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestExecuteBuffers(t *testing.T) {
+	for _, template := range []string{
+		"foo[foo]bar",
+		"foobar",
+	} {
+		tpl := New(template, "[", "]", BestCompression)
+
+		// Use a value large enough to span several deflate blocks.
+		foo := make([]byte, 96<<10)
+		for i := range foo {
+			foo[i] = byte(i * 7919 >> 3)
+		}
+
+		bufs, err := tpl.ExecuteBuffers(map[string]interface{}{"foo": foo})
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+
+		var buf bytes.Buffer
+		if _, err := bufs.WriteTo(&buf); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+
+		s := decompressBytes(t, buf.Bytes())
+		result := strings.Replace(template, "[foo]", string(foo), 1)
+		if string(s) != result {
+			t.Fatal("unexpected template value")
+		}
+	}
+}
+
+func TestExecuteBuffersSections(t *testing.T) {
+	tpl := New("<ul>[#range items]<li>[name]</li>[#end]</ul>[#if more]more[#end][v]", "[", "]", BestCompression)
+
+	bufs, err := tpl.ExecuteBuffers(map[string]interface{}{
+		"items": []map[string]interface{}{{"name": "a"}, {"name": "b"}},
+		"v":     NewValue([]byte("value"), BestCompression),
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	// The static segments are referenced rather than copied.
+	segs, err := tpl.rawSegments()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if &bufs[1][0] != &segs[0].b[0] {
+		t.Error("expected the first static segment to be referenced")
+	}
+
+	var buf bytes.Buffer
+	if _, err := bufs.WriteTo(&buf); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	s := decompressBytes(t, buf.Bytes())
+	result := "<ul><li>a</li><li>b</li></ul>value"
+	if string(s) != result {
+		t.Fatalf("unexpected template value %q. Expected %q", s, result)
+	}
+}
+
+func TestExecuteBuffersLimits(t *testing.T) {
+	tpl := New("foo[foo]bar", "[", "]", BestCompression, WithLimits(Limits{MaxOutput: 16}))
+
+	if _, err := tpl.ExecuteBuffers(map[string]interface{}{"foo": "x"}); err == nil {
+		t.Fatal("expected error")
+	}
+}
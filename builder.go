@@ -0,0 +1,163 @@
+package gziptemplate
+
+import (
+	"bytes"
+
+	"go.tmthrgd.dev/gzipbuilder"
+)
+
+// TemplateBuilder assembles a Template programmatically from static text
+// and tags, bypassing template parsing entirely.
+//
+// This suits tools that generate templates from ASTs or database rows, as
+// there are no delimiters to escape: text may contain any bytes and tag
+// names may contain any characters.
+//
+// Static text is precompressed as it is added. A TemplateBuilder is not safe
+// for concurrent use and must not be used after Template is called.
+type TemplateBuilder struct {
+	level int
+
+	w   *gzipbuilder.PrecompressedWriter
+	err error
+
+	texts []*gzipbuilder.PrecompressedData
+	tags  []tag
+
+	// n is the uncompressed length of the current segment.
+	n        int64
+	textLens []int64
+}
+
+// NewTemplateBuilder returns a TemplateBuilder for a template compressed at
+// the given level.
+func NewTemplateBuilder(level int) *TemplateBuilder {
+	return &TemplateBuilder{
+		level: level,
+
+		w: gzipbuilder.NewPrecompressedWriter(level),
+	}
+}
+
+// AddText appends static text to the template. Consecutive calls to AddText
+// are compressed together as a single segment.
+func (b *TemplateBuilder) AddText(p []byte) {
+	if b.err == nil {
+		b.w.Write(p)
+		b.n += int64(len(p))
+	}
+}
+
+// AddTag appends a tag (placeholder) named name to the template.
+func (b *TemplateBuilder) AddTag(name string) {
+	b.AddEscapedTag(name, EscapeNone)
+}
+
+// AddEscapedTag appends a tag (placeholder) named name to the template whose
+// values are always escaped according to e, however they are supplied.
+func (b *TemplateBuilder) AddEscapedTag(name string, e Escaping) {
+	b.addTag(tag{name: name, escape: e.escapeFunc()})
+}
+
+// addTag appends the value tag tg to the template.
+func (b *TemplateBuilder) addTag(tg tag) {
+	if b.flush() {
+		tg.pos = -1
+		b.tags = append(b.tags, tg)
+	}
+}
+
+// addOp appends a control tag to the template and returns its index, or -1
+// if the builder has failed.
+func (b *TemplateBuilder) addOp(op tagOp, name string) int {
+	if !b.flush() {
+		return -1
+	}
+
+	b.tags = append(b.tags, tag{
+		name: name,
+		pos:  -1,
+		op:   op,
+	})
+	return len(b.tags) - 1
+}
+
+// addTemplate appends the segments and tags of t to the template.
+func (b *TemplateBuilder) addTemplate(t *Template) {
+	if b.addOp(opNop, "") < 0 {
+		return
+	}
+
+	off := len(b.tags)
+	for _, tag := range t.tags {
+		tag.jump += off
+		tag.pos = -1
+		b.tags = append(b.tags, tag)
+	}
+
+	b.texts = append(b.texts, t.texts...)
+	b.textLens = append(b.textLens, t.textLens...)
+
+	b.tags = append(b.tags, tag{
+		pos: -1,
+		op:  opNop,
+	})
+}
+
+// flush completes the current segment and starts a new one. It reports
+// whether the builder is still free of errors.
+func (b *TemplateBuilder) flush() bool {
+	if b.err != nil {
+		return false
+	}
+
+	d, err := b.w.Data()
+	if err != nil {
+		b.err = err
+		return false
+	}
+
+	b.texts = append(b.texts, d)
+	b.textLens = append(b.textLens, b.n)
+	b.w.Reset()
+	b.n = 0
+	return true
+}
+
+// Template completes the template and returns it. It returns the first error
+// encountered while building the template, if any.
+func (b *TemplateBuilder) Template() (*Template, error) {
+	if !b.flush() {
+		return nil, b.err
+	}
+
+	t := &Template{
+		level: b.level,
+		texts: b.texts,
+		tags:  b.tags,
+
+		textLens: b.textLens,
+	}
+
+	if len(t.tags) == 0 {
+		var err error
+		if t.template, err = gzipSegment(t.level, t.texts[0]); err != nil {
+			return nil, err
+		}
+	}
+
+	return t, nil
+}
+
+// gzipSegment returns the complete gzip output of a single precompressed
+// segment.
+func gzipSegment(level int, d *gzipbuilder.PrecompressedData) ([]byte, error) {
+	var buf bytes.Buffer
+	gw := gzipbuilder.NewWriter(&buf, level)
+	gw.AddPrecompressedData(d)
+	if err := gw.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
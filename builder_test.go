@@ -0,0 +1,52 @@
+package gziptemplate
+
+import "testing"
+
+func TestTemplateBuilder(t *testing.T) {
+	b := NewTemplateBuilder(BestCompression)
+	b.AddText([]byte("foo[[not a tag]]"))
+	b.AddTag("foo")
+	b.AddTag("with ]] delimiters")
+	b.AddText([]byte("bar"))
+	b.AddText([]byte("baz"))
+
+	tpl, err := b.Template()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	s := tpl.ExecuteBytes(map[string]interface{}{
+		"foo":                "111",
+		"with ]] delimiters": "222",
+	})
+	s = decompressBytes(t, s)
+	result := "foo[[not a tag]]111222barbaz"
+	if string(s) != result {
+		t.Fatalf("unexpected template value %q. Expected %q", s, result)
+	}
+}
+
+func TestTemplateBuilderNoTags(t *testing.T) {
+	b := NewTemplateBuilder(BestCompression)
+	b.AddText([]byte("foobar"))
+
+	tpl, err := b.Template()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	s := decompressBytes(t, tpl.ExecuteBytes(nil))
+	if string(s) != "foobar" {
+		t.Fatalf("unexpected template value %q. Expected %q", s, "foobar")
+	}
+}
+
+func TestTemplateBuilderInvalidLevel(t *testing.T) {
+	b := NewTemplateBuilder(100)
+	b.AddText([]byte("foo"))
+	b.AddTag("foo")
+
+	if _, err := b.Template(); err == nil {
+		t.Fatalf("expected non-nil error. got nil")
+	}
+}
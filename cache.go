@@ -0,0 +1,219 @@
+package gziptemplate
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/binary"
+	"io"
+	"sort"
+	"sync"
+	"time"
+)
+
+// CacheOptions configures a CachedTemplate.
+type CacheOptions struct {
+	// MaxEntries is the maximum number of outputs held, the least
+	// recently used being evicted first. It defaults to 1024.
+	MaxEntries int
+
+	// TTL is how long an output is served from the cache. Zero means
+	// outputs remain until evicted.
+	TTL time.Duration
+}
+
+// CachedTemplate executes a template, caching its gzipped output keyed by
+// the values it was executed with. See Template.Cached.
+//
+// A CachedTemplate is safe for concurrent use by multiple goroutines.
+type CachedTemplate struct {
+	t    *Template
+	opts CacheOptions
+
+	mu      sync.Mutex
+	lru     *list.List
+	entries map[[sha256.Size]byte]*list.Element
+
+	now func() time.Time
+}
+
+// cacheEntry is an element of CachedTemplate.lru.
+type cacheEntry struct {
+	key     [sha256.Size]byte
+	b       []byte
+	expires time.Time
+}
+
+// Cached returns a CachedTemplate that executes t, serving repeated
+// executions with identical values from an LRU cache without compressing
+// anything.
+//
+// Values are hashed by content. Only maps whose values are all strings,
+// byte slices, bools, nil or slices of such maps, for repeated sections,
+// can be cached; others, such as those holding a TagFunc, are executed
+// afresh each time. Concurrent misses for the same values may each execute
+// t. Changes made with SetDefault or OnMissing after the first execution
+// are not reflected in outputs already cached.
+func (t *Template) Cached(opts CacheOptions) *CachedTemplate {
+	if opts.MaxEntries <= 0 {
+		opts.MaxEntries = 1024
+	}
+
+	return &CachedTemplate{
+		t:    t,
+		opts: opts,
+
+		lru:     list.New(),
+		entries: make(map[[sha256.Size]byte]*list.Element),
+
+		now: time.Now,
+	}
+}
+
+// Execute substitutes template tags (placeholders) with the corresponding
+// values from the map m and writes the gzipped result to w, as with
+// Template.Execute.
+func (c *CachedTemplate) Execute(w io.Writer, m map[string]interface{}) error {
+	b, err := c.ExecuteBytes(m)
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(b)
+	return err
+}
+
+// ExecuteBytes is like Execute but returns the gzipped result. It may be
+// shared with other executions and must not be modified.
+func (c *CachedTemplate) ExecuteBytes(m map[string]interface{}) ([]byte, error) {
+	key, ok := cacheKey(m)
+	if !ok {
+		return c.t.ExecuteBytesErr(m)
+	}
+
+	if b, ok := c.get(key); ok {
+		return b, nil
+	}
+
+	b, err := c.t.ExecuteBytesErr(m)
+	if err != nil {
+		return nil, err
+	}
+
+	c.put(key, b)
+	return b, nil
+}
+
+// Len returns the number of outputs in the cache, including any that have
+// expired but are yet to be evicted.
+func (c *CachedTemplate) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.lru.Len()
+}
+
+func (c *CachedTemplate) get(key [sha256.Size]byte) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+
+	e := el.Value.(*cacheEntry)
+	if !e.expires.IsZero() && !c.now().Before(e.expires) {
+		c.lru.Remove(el)
+		delete(c.entries, key)
+		return nil, false
+	}
+
+	c.lru.MoveToFront(el)
+	return e.b, true
+}
+
+func (c *CachedTemplate) put(key [sha256.Size]byte, b []byte) {
+	e := &cacheEntry{key: key, b: b}
+	if c.opts.TTL > 0 {
+		e.expires = c.now().Add(c.opts.TTL)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, ok := c.entries[key]; ok {
+		el.Value = e
+		c.lru.MoveToFront(el)
+		return
+	}
+
+	c.entries[key] = c.lru.PushFront(e)
+
+	for c.lru.Len() > c.opts.MaxEntries {
+		el := c.lru.Back()
+		c.lru.Remove(el)
+		delete(c.entries, el.Value.(*cacheEntry).key)
+	}
+}
+
+// cacheKey returns the hash of the contents of m. It reports false if m
+// holds a value that cannot be hashed.
+func cacheKey(m map[string]interface{}) ([sha256.Size]byte, bool) {
+	var key [sha256.Size]byte
+
+	h := sha256.New()
+	if !hashMap(h, m) {
+		return key, false
+	}
+
+	h.Sum(key[:0])
+	return key, true
+}
+
+// hashMap writes an unambiguous encoding of m to h.
+func hashMap(h io.Writer, m map[string]interface{}) bool {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	hashBytes(h, 'm', len(keys), nil)
+	for _, k := range keys {
+		hashBytes(h, 'k', len(k), []byte(k))
+
+		switch v := m[k].(type) {
+		case nil:
+			hashBytes(h, 'n', 0, nil)
+		case string:
+			hashBytes(h, 's', len(v), []byte(v))
+		case []byte:
+			hashBytes(h, 'b', len(v), v)
+		case bool:
+			if v {
+				hashBytes(h, 't', 0, nil)
+			} else {
+				hashBytes(h, 'f', 0, nil)
+			}
+		case []map[string]interface{}:
+			hashBytes(h, 'r', len(v), nil)
+			for _, item := range v {
+				if !hashMap(h, item) {
+					return false
+				}
+			}
+		default:
+			return false
+		}
+	}
+
+	return true
+}
+
+// hashBytes writes typ, n and b to h.
+func hashBytes(h io.Writer, typ byte, n int, b []byte) {
+	var hdr [9]byte
+	hdr[0] = typ
+	binary.LittleEndian.PutUint64(hdr[1:], uint64(n))
+	h.Write(hdr[:])
+	h.Write(b)
+}
@@ -0,0 +1,83 @@
+package gziptemplate
+
+import (
+	"bytes"
+	"io"
+	"testing"
+	"time"
+)
+
+func TestCached(t *testing.T) {
+	tpl := New("foo[bar][#range items][x][#end]", "[", "]", BestCompression)
+	tpl.EnableStats()
+
+	c := tpl.Cached(CacheOptions{MaxEntries: 2, TTL: time.Minute})
+	now := time.Now()
+	c.now = func() time.Time { return now }
+
+	m := func(bar string) map[string]interface{} {
+		return map[string]interface{}{
+			"bar":   bar,
+			"items": []map[string]interface{}{{"x": []byte("1")}, {"x": "2"}},
+		}
+	}
+
+	for _, bar := range []string{"a", "a", "b", "a", "b"} {
+		var buf bytes.Buffer
+		if err := c.Execute(&buf, m(bar)); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+
+		if s := decompressBytes(t, buf.Bytes()); string(s) != "foo"+bar+"12" {
+			t.Fatalf("unexpected template value %q. Expected %q", s, "foo"+bar+"12")
+		}
+	}
+	if n := tpl.Stats().Executions; n != 2 {
+		t.Fatalf("unexpected number of executions %d. Expected 2", n)
+	}
+
+	// Evicts "a", the least recently used.
+	c.ExecuteBytes(m("c"))
+	c.ExecuteBytes(m("a"))
+	if n := tpl.Stats().Executions; n != 4 {
+		t.Fatalf("unexpected number of executions %d. Expected 4", n)
+	}
+	if n := c.Len(); n != 2 {
+		t.Fatalf("unexpected cache length %d. Expected 2", n)
+	}
+
+	now = now.Add(time.Minute)
+	c.ExecuteBytes(m("a"))
+	if n := tpl.Stats().Executions; n != 5 {
+		t.Fatalf("unexpected number of executions %d. Expected 5", n)
+	}
+
+	// Values that cannot be hashed are never cached.
+	f := map[string]interface{}{"bar": TagFunc(func(w io.Writer, tag string) error {
+		_, err := io.WriteString(w, "f")
+		return err
+	})}
+	c.ExecuteBytes(f)
+	c.ExecuteBytes(f)
+	if n := tpl.Stats().Executions; n != 7 {
+		t.Fatalf("unexpected number of executions %d. Expected 7", n)
+	}
+}
+
+func TestCacheKey(t *testing.T) {
+	for _, pair := range [][2]map[string]interface{}{
+		{{"a": "bc"}, {"ab": "c"}},
+		{{"a": "1"}, {"a": []byte("1")}},
+		{{"a": true}, {"a": false}},
+		{{"a": nil}, {}},
+	} {
+		k0, ok0 := cacheKey(pair[0])
+		k1, ok1 := cacheKey(pair[1])
+		if !ok0 || !ok1 {
+			t.Fatalf("expected %v and %v to be hashable", pair[0], pair[1])
+		}
+		if k0 == k1 {
+			t.Fatalf("%v and %v have the same key", pair[0], pair[1])
+		}
+	}
+}
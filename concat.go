@@ -0,0 +1,48 @@
+package gziptemplate
+
+// Append returns a template that writes the output of t followed by that of
+// other. Neither t nor other is modified.
+//
+// The precompressed segments of both are reused rather than compressed
+// again, so pages may be cheaply composed from a header, body and footer.
+// The result takes the name, middleware, delimiters and other settings of t
+// and the defaults and registered values of both, with those of t taking
+// precedence.
+func (t *Template) Append(other *Template) *Template {
+	nt := t.cloneSettings(nil)
+
+	nt.texts = append(nt.texts, t.texts...)
+	nt.texts = append(nt.texts, other.texts...)
+	nt.textLens = append(nt.textLens, t.textLens...)
+	nt.textLens = append(nt.textLens, other.textLens...)
+
+	// The last segment of t and the first of other can't be joined, so
+	// they are separated by a no-op tag.
+	nt.tags = make([]tag, 0, len(t.tags)+1+len(other.tags))
+	nt.tags = append(nt.tags, t.tags...)
+	nt.tags = append(nt.tags, tag{op: opNop})
+
+	off := len(nt.tags)
+	for _, tag := range other.tags {
+		tag.jump += off
+		nt.tags = append(nt.tags, tag)
+	}
+
+	// The tags now come from two sources.
+	for i := range nt.tags {
+		nt.tags[i].pos = -1
+	}
+
+	for tag, v := range other.defaults {
+		if _, ok := nt.defaults[tag]; !ok {
+			nt.SetDefault(tag, v)
+		}
+	}
+	for tag, values := range other.registered {
+		if _, ok := nt.registered[tag]; !ok {
+			nt.registerValues(tag, values)
+		}
+	}
+
+	return nt
+}
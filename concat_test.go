@@ -0,0 +1,41 @@
+package gziptemplate
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestAppend(t *testing.T) {
+	header := New("<header>[title]</header>", "[", "]", BestCompression)
+	header.SetDefault("title", "Header")
+	body := New("<main>[#if body][body][#else]empty[#end]</main>", "[", "]", BestCompression)
+	footer := New("<footer/>", "[", "]", BestCompression)
+
+	page := header.Append(body).Append(footer)
+
+	m := map[string]interface{}{"body": "Body"}
+	result := "<header>Header</header><main>Body</main><footer/>"
+
+	s := decompressBytes(t, page.ExecuteBytes(m))
+	if string(s) != result {
+		t.Fatalf("unexpected template value %q. Expected %q", s, result)
+	}
+
+	var buf bytes.Buffer
+	if err := page.Execute(&buf, nil); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	result = "<header>Header</header><main>empty</main><footer/>"
+	if s := decompressBytes(t, buf.Bytes()); string(s) != result {
+		t.Fatalf("unexpected template value %q. Expected %q", s, result)
+	}
+
+	s = decompressBytes(t, footer.Append(footer).ExecuteBytes(nil))
+	if string(s) != "<footer/><footer/>" {
+		t.Fatalf("unexpected template value %q. Expected %q", s, "<footer/><footer/>")
+	}
+}
+
+func TestAppendKeepsSettings(t *testing.T) {
+	checkSettings(t, settingsTemplate(t).Append(New("!", "[", "]", BestCompression)), "<p>a; b</p>Untitled!")
+}
@@ -0,0 +1,58 @@
+package gziptemplate
+
+import (
+	"context"
+	"io"
+)
+
+// TagFuncContext is like TagFunc but is also passed the context of the
+// execution. It may be used with ExecuteFuncContext or as a value in the map
+// passed to ExecuteContext.
+type TagFuncContext func(ctx context.Context, w io.Writer, tag string) error
+
+// ExecuteFuncContext is like ExecuteFunc but passes ctx to f and stops once
+// ctx is done. The context is checked before each tag is substituted, so a
+// long running f should also watch ctx itself.
+//
+// The error returned once ctx is done is an *ExecError wrapping ctx.Err(),
+// so it should be checked with errors.Is, as in
+// errors.Is(err, context.Canceled).
+//
+// This allows renders backed by slow TagFuncs, such as database lookups, to
+// be cancelled when the client goes away. Output may already have been
+// written to w when an error is returned.
+func (t *Template) ExecuteFuncContext(ctx context.Context, w io.Writer, f TagFuncContext) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	return t.ExecuteFunc(w, func(w io.Writer, tag string) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		return f(ctx, w, tag)
+	})
+}
+
+// ExecuteContext is like Execute but stops with ctx.Err() once ctx is done.
+// TagFuncContext values in m are passed ctx.
+//
+// See ExecuteFuncContext for details.
+func (t *Template) ExecuteContext(ctx context.Context, w io.Writer, m map[string]interface{}) error {
+	base := t.mapLookup(m)
+	lookup := func(tag string) (interface{}, bool) {
+		v, ok := base(tag)
+		if fc, isFunc := v.(TagFuncContext); isFunc {
+			v = TagFunc(func(w io.Writer, tag string) error {
+				return fc(ctx, w, tag)
+			})
+		}
+		return v, ok
+	}
+
+	f := t.lookupTagFunc(lookup)
+	return t.ExecuteFuncContext(ctx, w, func(_ context.Context, w io.Writer, tag string) error {
+		return f(w, tag)
+	})
+}
@@ -0,0 +1,51 @@
+package gziptemplate
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"testing"
+)
+
+type ctxKey struct{}
+
+func TestExecuteContext(t *testing.T) {
+	tpl := New("a[foo]b[bar]c", "[", "]", BestCompression)
+
+	ctx, cancel := context.WithCancel(context.WithValue(context.Background(), ctxKey{}, "value"))
+	defer cancel()
+
+	m := map[string]interface{}{
+		"foo": TagFuncContext(func(ctx context.Context, w io.Writer, tag string) error {
+			_, err := io.WriteString(w, ctx.Value(ctxKey{}).(string))
+			return err
+		}),
+		"bar": "1",
+	}
+
+	var buf bytes.Buffer
+	if err := tpl.ExecuteContext(ctx, &buf, m); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if s := decompressBytes(t, buf.Bytes()); string(s) != "avalueb1c" {
+		t.Fatalf("unexpected template value %q. Expected %q", s, "avalueb1c")
+	}
+
+	var calls int
+	err := tpl.ExecuteFuncContext(ctx, &buf, func(ctx context.Context, w io.Writer, tag string) error {
+		calls++
+		cancel()
+		return nil
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	if calls != 1 {
+		t.Fatalf("unexpected number of TagFunc calls %d. Expected %d", calls, 1)
+	}
+
+	if err := tpl.ExecuteContext(ctx, &buf, m); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+}
@@ -0,0 +1,96 @@
+package gziptemplate
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"time"
+)
+
+// TimeoutError is returned by ExecuteDeadline when rendering takes longer
+// than allowed. It can be detected with errors.As, or with errors.Is and
+// context.DeadlineExceeded.
+type TimeoutError struct {
+	// Timeout is the duration that was exceeded.
+	Timeout time.Duration
+}
+
+func (e *TimeoutError) Error() string {
+	return fmt.Sprintf("gziptemplate: execution exceeded deadline of %s", e.Timeout)
+}
+
+// Unwrap returns context.DeadlineExceeded.
+func (e *TimeoutError) Unwrap() error {
+	return context.DeadlineExceeded
+}
+
+// ExecuteFuncDeadline is like ExecuteFunc but gives up with a *TimeoutError
+// if rendering takes longer than d.
+//
+// The template is rendered into a buffer by another goroutine and written to
+// w only once complete, so nothing is written to w on timeout. The caller is
+// freed at the deadline even if f never returns; a stuck f is left to
+// finish in the background, after which no further tags are substituted.
+//
+// A panic while rendering, such as one raised by WithPanicOnError, is
+// raised again in the caller if it happens before the deadline and is
+// otherwise discarded.
+func (t *Template) ExecuteFuncDeadline(w io.Writer, f TagFunc, d time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), d)
+	defer cancel()
+
+	var buf bytes.Buffer
+	done := make(chan deadlineResult, 1)
+	go func() {
+		var res deadlineResult
+		defer func() {
+			if res.panic = recover(); res.panic != nil {
+				res.panicked = true
+			}
+			done <- res
+		}()
+
+		res.err = t.ExecuteFuncContext(ctx, &buf, func(_ context.Context, w io.Writer, tag string) error {
+			return f(w, tag)
+		})
+	}()
+
+	select {
+	case res := <-done:
+		if res.panicked {
+			panic(res.panic)
+		}
+
+		err := res.err
+		if err != nil && ctx.Err() == context.DeadlineExceeded {
+			return &TimeoutError{d}
+		}
+		if err != nil {
+			return err
+		}
+	case <-ctx.Done():
+		return &TimeoutError{d}
+	}
+
+	_, err := buf.WriteTo(w)
+	return err
+}
+
+// ExecuteDeadline is like Execute but gives up with a *TimeoutError if
+// rendering takes longer than d.
+//
+// See ExecuteFuncDeadline for details.
+func (t *Template) ExecuteDeadline(w io.Writer, m map[string]interface{}, d time.Duration) error {
+	return t.ExecuteFuncDeadline(w, t.mapTagFunc(m), d)
+}
+
+// deadlineResult is the outcome of the rendering goroutine of
+// ExecuteFuncDeadline.
+type deadlineResult struct {
+	err error
+
+	// panic is the value recovered from a panic, if panicked.
+	panic    interface{}
+	panicked bool
+}
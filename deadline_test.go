@@ -0,0 +1,58 @@
+package gziptemplate
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"testing"
+	"time"
+)
+
+func TestExecuteDeadline(t *testing.T) {
+	tpl := New("foo[bar]baz", "[", "]", BestCompression)
+
+	var buf bytes.Buffer
+	if err := tpl.ExecuteDeadline(&buf, map[string]interface{}{"bar": "111"}, time.Minute); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if s := decompressBytes(t, buf.Bytes()); string(s) != "foo111baz" {
+		t.Fatalf("unexpected template value %q. Expected %q", s, "foo111baz")
+	}
+
+	stuck := make(chan struct{})
+	defer close(stuck)
+
+	buf.Reset()
+	err := tpl.ExecuteFuncDeadline(&buf, func(w io.Writer, tag string) error {
+		<-stuck
+		return nil
+	}, 10*time.Millisecond)
+
+	var te *TimeoutError
+	if !errors.As(err, &te) || te.Timeout != 10*time.Millisecond {
+		t.Fatalf("expected *TimeoutError, got %#v", err)
+	}
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected error to wrap %v, got %v", context.DeadlineExceeded, err)
+	}
+	if buf.Len() != 0 {
+		t.Fatalf("unexpected output %q after timeout", buf.Bytes())
+	}
+}
+
+func TestExecuteDeadlinePanic(t *testing.T) {
+	tpl := New("foo[bar]baz", "[", "]", BestCompression)
+
+	defer func() {
+		if r := recover(); r != "boom" {
+			t.Fatalf("expected panic %q to reach the caller, got %v", "boom", r)
+		}
+	}()
+
+	tpl.ExecuteFuncDeadline(ioutil.Discard, func(w io.Writer, tag string) error {
+		panic("boom")
+	}, time.Minute)
+	t.Fatal("expected panic")
+}
@@ -0,0 +1,163 @@
+package gziptemplate
+
+import (
+	"bytes"
+	"io"
+
+	"go.tmthrgd.dev/gzipbuilder"
+)
+
+// Derive returns a copy of t with the tags given a value in m substituted
+// now, as with Execute, leaving only the remaining tags to be substituted at
+// execution time.
+//
+// Each substituted value is merged with the static segments either side of
+// it, which are compressed again. Segments not next to a substituted tag are
+// shared with t. This suits values, such as per-tenant settings, that never
+// change between executions and shouldn't cost deflate work on each of them.
+//
+// Middleware and escaping apply to the substituted values as they are when
+// Derive is called. The defaults of substituted tags are dropped. Tags within
+// repeated sections are never substituted as their value may come from the
+// items.
+//
+// Derive is not named Bind as Bind returns a substitution value that renders
+// the template, for nesting one template within another.
+func (t *Template) Derive(m map[string]interface{}) (*Template, error) {
+	plain, err := t.plainTexts()
+	if err != nil {
+		return nil, err
+	}
+
+	f := t.mapTagFunc(m)
+	if len(t.middleware) != 0 {
+		f = Chain(f, t.middleware...)
+	}
+
+	nt := t.cloneSettings(nil)
+	if t.textPos != nil {
+		nt.textPos = make([]int64, 0, len(t.textPos))
+	}
+
+	var (
+		text   bytes.Buffer
+		merged bool
+
+		// index maps the tags of t to those of nt.
+		index = make([]int, len(t.tags))
+
+		// ranges holds the ends of the repeated sections being
+		// walked.
+		ranges []int
+
+		// folded holds the opElse tags of the defaults of substituted
+		// tags, and skip the index of the first tag not yet dropped.
+		folded = make(map[int]bool)
+		skip   int
+	)
+	flush := func(i int) error {
+		if !merged {
+			nt.texts = append(nt.texts, t.texts[i])
+			nt.textLens = append(nt.textLens, t.textLens[i])
+			if nt.textPos != nil {
+				nt.textPos = append(nt.textPos, t.textPos[i])
+			}
+			return nil
+		}
+
+		w := gzipbuilder.NewPrecompressedWriter(t.level)
+		w.Write(text.Bytes())
+		d, err := w.Data()
+		if err != nil {
+			return err
+		}
+
+		nt.texts = append(nt.texts, d)
+		nt.textLens = append(nt.textLens, int64(text.Len()))
+		if nt.textPos != nil {
+			nt.textPos = append(nt.textPos, -1)
+		}
+		return nil
+	}
+
+	text.Write(plain[0])
+	for i, tag := range t.tags {
+		if i < skip {
+			continue
+		}
+
+		for len(ranges) != 0 && ranges[len(ranges)-1] <= i {
+			ranges = ranges[:len(ranges)-1]
+		}
+
+		_, bind := resolve(t.mapLookup(m), tag.name)
+		switch {
+		case tag.op == opHas && len(ranges) == 0 && bind:
+			// A tag with a default, [name|def], is compiled as
+			// opHas, the value, opElse, def and opEnd. Once the
+			// value is substituted only it remains.
+			folded[tag.jump] = true
+			text.Write(plain[i+1])
+			merged = true
+			continue
+		case folded[i]:
+			skip = tag.jump + 1
+			text.Write(plain[tag.jump+1])
+			merged = true
+			continue
+		}
+
+		if tag.op != opValue || len(ranges) != 0 || !bind {
+			if tag.op == opRange {
+				ranges = append(ranges, tag.jump)
+			}
+
+			if err := flush(i); err != nil {
+				return nil, err
+			}
+
+			index[i] = len(nt.tags)
+			nt.tags = append(nt.tags, tag)
+
+			text.Reset()
+			text.Write(plain[i+1])
+			merged = false
+			continue
+		}
+
+		var w io.Writer = &text
+		if escape := tag.escaper(); escape != nil {
+			w = &escapeWriter{w: &text, escape: escape}
+		}
+		if err := f(w, tag.name); err != nil {
+			return nil, err
+		}
+
+		text.Write(plain[i+1])
+		merged = true
+	}
+	if err := flush(len(t.texts) - 1); err != nil {
+		return nil, err
+	}
+
+	for i := range nt.tags {
+		switch nt.tags[i].op {
+		case opIf, opElse, opRange, opHas, opBlock:
+			nt.tags[i].jump = index[nt.tags[i].jump]
+		}
+	}
+
+	if len(nt.tags) == 0 {
+		if nt.template, err = gzipSegment(nt.level, nt.texts[0]); err != nil {
+			return nil, err
+		}
+	}
+
+	if t.patch != nil {
+		if nt.patch, err = newPatchImage(nt, t.patch.tagWidths(t)); err != nil {
+			return nil, err
+		}
+	}
+
+	return nt, nil
+}
@@ -0,0 +1,81 @@
+package gziptemplate
+
+import "testing"
+
+func TestDerive(t *testing.T) {
+	tpl := New("<h1>[tenant]</h1>[#if user]hi [user][#else]anon[#end] [#range items]<[tenant]>[#end] [name|html]", "[", "]", BestCompression)
+
+	dt, err := tpl.Derive(map[string]interface{}{
+		"tenant": "acme",
+		"name":   "<b>",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	for _, tag := range dt.tags {
+		if tag.op == opValue && tag.name == "name" {
+			t.Errorf("tag %q was not substituted", tag.name)
+		}
+	}
+
+	m := map[string]interface{}{
+		"user":   "bob",
+		"tenant": "other",
+		"items":  []map[string]interface{}{{"tenant": "x"}, {}},
+	}
+	s := decompressBytes(t, dt.ExecuteBytes(m))
+	result := "<h1>acme</h1>hi bob <x><other> &lt;b&gt;"
+	if string(s) != result {
+		t.Fatalf("unexpected template value %q. Expected %q", s, result)
+	}
+
+	s = decompressBytes(t, tpl.ExecuteBytes(map[string]interface{}{"tenant": "t"}))
+	result = "<h1>t</h1>anon  "
+	if string(s) != result {
+		t.Fatalf("unexpected template value %q. Expected %q", s, result)
+	}
+
+	static := New("a[foo]b", "[", "]", BestCompression)
+	dt, err = static.Derive(map[string]interface{}{"foo": "-"})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if s := decompressBytes(t, dt.ExecuteBytes(nil)); string(s) != "a-b" {
+		t.Fatalf("unexpected template value %q. Expected %q", s, "a-b")
+	}
+	if len(dt.tags) != 0 {
+		t.Fatalf("expected no tags, got %d", len(dt.tags))
+	}
+}
+
+func TestDeriveDefault(t *testing.T) {
+	tpl := New("a[name|def]b[other|x]c", "[", "]", BestCompression)
+
+	dt, err := tpl.Derive(map[string]interface{}{"name": "X"})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	for _, test := range []struct {
+		m      map[string]interface{}
+		result string
+	}{
+		{nil, "aXbxc"},
+		{map[string]interface{}{"other": "Y"}, "aXbYc"},
+	} {
+		s := decompressBytes(t, dt.ExecuteBytes(test.m))
+		if string(s) != test.result {
+			t.Errorf("unexpected template value %q. Expected %q", s, test.result)
+		}
+	}
+}
+
+func TestDeriveKeepsSettings(t *testing.T) {
+	nt, err := settingsTemplate(t).Derive(map[string]interface{}{})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	checkSettings(t, nt, "<p>a; b</p>Untitled")
+}
@@ -0,0 +1,146 @@
+package gziptemplate
+
+import (
+	"io"
+	"net/url"
+	"strings"
+	"text/template"
+)
+
+// Escaping is an escaping policy applied to the values of a tag.
+type Escaping int
+
+// These are the supported escaping policies.
+const (
+	// EscapeNone writes values verbatim.
+	EscapeNone Escaping = iota
+
+	// EscapeHTML escapes values for use as HTML text.
+	EscapeHTML
+
+	// EscapeAttr escapes values for use within quoted HTML attribute
+	// values.
+	EscapeAttr
+
+	// EscapeURL escapes values for use as URL query components.
+	EscapeURL
+
+	// EscapeJS escapes values for use within JavaScript string
+	// literals.
+	EscapeJS
+)
+
+// Escaped is a substitution value that is escaped with the given policy
+// when written, so that the escaping intent lives with the data. It may be
+// used as a value in the map passed to Execute*.
+//
+// Value may be of any type supported by Execute other than *Template. It is
+// escaped in addition to any escaping of the tag it is substituted for.
+type Escaped struct {
+	Value    interface{}
+	Escaping Escaping
+}
+
+// HTML returns v to be escaped as HTML text.
+func HTML(v interface{}) Escaped {
+	return Escaped{v, EscapeHTML}
+}
+
+// HTMLAttr returns v to be escaped for use within a quoted HTML attribute
+// value.
+func HTMLAttr(v interface{}) Escaped {
+	return Escaped{v, EscapeAttr}
+}
+
+// URLQuery returns v to be escaped as a URL query component.
+func URLQuery(v interface{}) Escaped {
+	return Escaped{v, EscapeURL}
+}
+
+// JSString returns v to be escaped for use within a JavaScript string
+// literal.
+func JSString(v interface{}) Escaped {
+	return Escaped{v, EscapeJS}
+}
+
+// writeTo writes e for tag to w.
+func (e Escaped) writeTo(w io.Writer, tag string) error {
+	if escape := e.Escaping.escapeFunc(); escape != nil {
+		w = &escapeWriter{w: w, escape: escape}
+	}
+
+	return writeValue(w, tag, e.Value)
+}
+
+// escapeFunc writes p to w, escaped.
+type escapeFunc func(w io.Writer, p []byte) error
+
+func (e Escaping) escapeFunc() escapeFunc {
+	switch e {
+	case EscapeNone:
+		return nil
+	case EscapeHTML, EscapeAttr:
+		return escapeHTML
+	case EscapeURL:
+		return escapeURL
+	case EscapeJS:
+		return escapeJS
+	default:
+		panic("gziptemplate: invalid Escaping")
+	}
+}
+
+var htmlReplacer = strings.NewReplacer(
+	"&", "&amp;",
+	"<", "&lt;",
+	">", "&gt;",
+	`"`, "&#34;",
+	"'", "&#39;",
+	"\x00", "�",
+)
+
+func escapeHTML(w io.Writer, p []byte) error {
+	_, err := htmlReplacer.WriteString(w, string(p))
+	return err
+}
+
+func escapeURL(w io.Writer, p []byte) error {
+	_, err := io.WriteString(w, url.QueryEscape(string(p)))
+	return err
+}
+
+func escapeJS(w io.Writer, p []byte) error {
+	ew := &errWriter{w: w}
+	template.JSEscape(ew, p)
+	return ew.err
+}
+
+// escapeWriter escapes everything written to it.
+type escapeWriter struct {
+	w      io.Writer
+	escape escapeFunc
+}
+
+func (ew *escapeWriter) Write(p []byte) (int, error) {
+	if err := ew.escape(ew.w, p); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// errWriter records the first error returned by w and discards all writes
+// thereafter.
+type errWriter struct {
+	w   io.Writer
+	err error
+}
+
+func (ew *errWriter) Write(p []byte) (int, error) {
+	if ew.err != nil {
+		return 0, ew.err
+	}
+
+	n, err := ew.w.Write(p)
+	ew.err = err
+	return n, err
+}
@@ -0,0 +1,82 @@
+package gziptemplate
+
+import (
+	"io"
+	"testing"
+)
+
+func TestWithEscaping(t *testing.T) {
+	template := `<a href="/?q=[url]" title="[attr]" onclick="f('[js]')">[html][raw]</a>`
+	tpl := New(template, "[", "]", BestCompression, WithEscaping(map[string]Escaping{
+		"html": EscapeHTML,
+		"attr": EscapeAttr,
+		"url":  EscapeURL,
+		"js":   EscapeJS,
+		"raw":  EscapeNone,
+	}))
+
+	value := `<b>"it's" & a=b</b>`
+	s := tpl.ExecuteBytes(map[string]interface{}{
+		"html": value,
+		"attr": []byte(value),
+		"url":  value,
+		"js": TagFunc(func(w io.Writer, tag string) error {
+			_, err := io.WriteString(w, value)
+			return err
+		}),
+		"raw": "<br>",
+	})
+	s = decompressBytes(t, s)
+
+	result := `<a href="/?q=%3Cb%3E%22it%27s%22+%26+a%3Db%3C%2Fb%3E" ` +
+		`title="&lt;b&gt;&#34;it&#39;s&#34; &amp; a=b&lt;/b&gt;" ` +
+		`onclick="f('\u003Cb\u003E\"it\'s\" \u0026 a\u003Db\u003C/b\u003E')">` +
+		`&lt;b&gt;&#34;it&#39;s&#34; &amp; a=b&lt;/b&gt;<br></a>`
+	if string(s) != result {
+		t.Fatalf("unexpected template value %q. Expected %q", s, result)
+	}
+}
+
+func TestTemplateBuilderAddEscapedTag(t *testing.T) {
+	b := NewTemplateBuilder(BestCompression)
+	b.AddText([]byte("<p>"))
+	b.AddEscapedTag("foo", EscapeHTML)
+	b.AddText([]byte("</p>"))
+
+	tpl, err := b.Template()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	s := decompressBytes(t, tpl.ExecuteBytes(map[string]interface{}{"foo": "a<b"}))
+	result := "<p>a&lt;b</p>"
+	if string(s) != result {
+		t.Fatalf("unexpected template value %q. Expected %q", s, result)
+	}
+}
+
+func TestEscapedValues(t *testing.T) {
+	template := `<a href="/?q=[url]" title="[attr]" onclick="f('[js]')">[html][raw]</a>`
+	tpl := New(template, "[", "]", BestCompression)
+
+	value := `<b>"it's" & a=b</b>`
+	s := tpl.ExecuteBytes(map[string]interface{}{
+		"html": HTML(value),
+		"attr": HTMLAttr([]byte(value)),
+		"url":  URLQuery(value),
+		"js": JSString(TagFunc(func(w io.Writer, tag string) error {
+			_, err := io.WriteString(w, value)
+			return err
+		})),
+		"raw": Escaped{"<br>", EscapeNone},
+	})
+	s = decompressBytes(t, s)
+
+	result := `<a href="/?q=%3Cb%3E%22it%27s%22+%26+a%3Db%3C%2Fb%3E" ` +
+		`title="&lt;b&gt;&#34;it&#39;s&#34; &amp; a=b&lt;/b&gt;" ` +
+		`onclick="f('\u003Cb\u003E\"it\'s\" \u0026 a\u003Db\u003C/b\u003E')">` +
+		`&lt;b&gt;&#34;it&#39;s&#34; &amp; a=b&lt;/b&gt;<br></a>`
+	if string(s) != result {
+		t.Fatalf("unexpected template value %q. Expected %q", s, result)
+	}
+}
@@ -0,0 +1,91 @@
+package gziptemplate
+
+import "go.tmthrgd.dev/gzipbuilder"
+
+// EstimateSize returns an estimate of the size of the output of Execute
+// given the map m, without executing the template.
+//
+// The precompressed static segments are accounted for exactly. []byte and
+// string values are assumed to be incompressible and so are counted at the
+// size of the stored deflate blocks they would occupy, which usually makes
+// the estimate an upper bound. Values of other types, such as TagFunc, are
+// not counted.
+//
+// This allows handlers to choose between buffered and streaming responses
+// and to preallocate buffers sensibly.
+func (t *Template) EstimateSize(m map[string]interface{}) (int64, error) {
+	if len(t.tags) == 0 {
+		return int64(len(t.template)), nil
+	}
+	if t.patch != nil {
+		return int64(len(t.patch.image)), nil
+	}
+
+	sizes, extra, err := t.segmentSizes()
+	if err != nil {
+		return 0, err
+	}
+
+	n := extra
+	for _, size := range sizes {
+		n += size
+	}
+
+	for _, tag := range t.tags {
+		if tag.op != opValue {
+			continue
+		}
+
+		v, ok := m[tag.name]
+		if !ok {
+			v = t.defaults[tag.name]
+		}
+
+		var l int64
+		switch value := v.(type) {
+		case []byte:
+			l = int64(len(value))
+		case string:
+			l = int64(len(value))
+		}
+
+		// Each stored block holds up to 65535 bytes behind a 5 byte
+		// header.
+		if l > 0 {
+			n += l + 5*((l+0xfffe)/0xffff)
+		}
+	}
+
+	return n, nil
+}
+
+// segmentSizes returns the compressed size of each static segment of t and
+// the size of the gzip framing that surrounds them. They are measured on
+// first use.
+func (t *Template) segmentSizes() ([]int64, int64, error) {
+	t.sizeOnce.Do(func() {
+		var cw countWriter
+		gw := gzipbuilder.NewWriter(&cw, t.level)
+		if t.sizeErr = gw.Close(); t.sizeErr != nil {
+			return
+		}
+
+		extra := int64(cw)
+
+		sizes := make([]int64, len(t.texts))
+		for i, d := range t.texts {
+			cw = 0
+			gw := gzipbuilder.NewWriter(&cw, t.level)
+			gw.AddPrecompressedData(d)
+			if t.sizeErr = gw.Close(); t.sizeErr != nil {
+				return
+			}
+
+			sizes[i] = int64(cw) - extra
+		}
+
+		t.sizes, t.sizeExtra = sizes, extra
+	})
+
+	return t.sizes, t.sizeExtra, t.sizeErr
+}
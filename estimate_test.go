@@ -0,0 +1,33 @@
+package gziptemplate
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func TestEstimateSize(t *testing.T) {
+	random := make([]byte, 1<<17)
+	rand.New(rand.NewSource(1)).Read(random)
+
+	for _, template := range []string{
+		"foobar",
+		strings.Repeat("foo[foo]", 100) + "bar[bar]baz",
+	} {
+		tpl := New(template, "[", "]", BestCompression)
+
+		m := map[string]interface{}{
+			"foo": "111",
+			"bar": random,
+		}
+		n, err := tpl.EstimateSize(m)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+
+		size := int64(len(tpl.ExecuteBytes(m)))
+		if n < size-size/100 || n > size+size/10 {
+			t.Fatalf("unexpected estimate %d for output of %d bytes", n, size)
+		}
+	}
+}
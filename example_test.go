@@ -8,6 +8,7 @@ import (
 	"io/ioutil"
 	"log"
 	"net/url"
+	"strings"
 )
 
 func mustDecompress(b []byte) []byte {
@@ -85,6 +86,23 @@ func ExampleTagFunc() {
 	// foo123456789bar
 }
 
+func ExampleURLQuery() {
+	t, err := NewTemplate("https://example.com/?q=[query]&lang=[lang]", "[", "]", BestCompression)
+	if err != nil {
+		log.Fatalf("unexpected error when parsing template: %s", err)
+	}
+
+	s := t.ExecuteBytes(map[string]interface{}{
+		"query": URLQuery("fish & chips"),
+		"lang":  "en",
+	})
+	s = mustDecompress(s)
+	fmt.Printf("%s", s)
+
+	// Output:
+	// https://example.com/?q=fish+%26+chips&lang=en
+}
+
 func ExampleTemplate_ExecuteFuncBytes() {
 	template := "Hello, [user]! You won [prize]!!! [foobar]"
 	t, err := NewTemplate(template, "[", "]", BestCompression)
@@ -110,3 +128,46 @@ func ExampleTemplate_ExecuteFuncBytes() {
 	// Output:
 	// Hello, John! You won $100500!!! [unknown tag "foobar"]
 }
+
+func ExampleNewTemplateFromReader() {
+	// The reader could equally be a multi-megabyte *os.File, which is
+	// compressed as it is read without being held in memory.
+	r := strings.NewReader("<html><body>[body]</body></html>")
+
+	t, err := NewTemplateFromReader(r, "[", "]", BestCompression)
+	if err != nil {
+		log.Fatalf("unexpected error when parsing template: %s", err)
+	}
+
+	s := t.ExecuteBytes(map[string]interface{}{"body": "Hello"})
+	s = mustDecompress(s)
+	fmt.Printf("%s", s)
+
+	// Output:
+	// <html><body>Hello</body></html>
+}
+
+func ExampleTemplateSet_Bind() {
+	set := NewTemplateSet()
+	if _, err := set.Parse("card", "<div>[title]: [body]</div>", "[", "]", BestCompression); err != nil {
+		log.Fatalf("unexpected error when parsing template: %s", err)
+	}
+
+	page, err := NewTemplate("<h1>[title]</h1>[first][second]", "[", "]", BestCompression)
+	if err != nil {
+		log.Fatalf("unexpected error when parsing template: %s", err)
+	}
+
+	// Each card is rendered with its own values, and its precompressed
+	// segments are spliced into the page.
+	s := page.ExecuteBytes(map[string]interface{}{
+		"title":  "Cards",
+		"first":  set.Bind("card", map[string]interface{}{"title": "One", "body": "a"}),
+		"second": set.Bind("card", map[string]interface{}{"title": "Two", "body": "b"}),
+	})
+	s = mustDecompress(s)
+	fmt.Printf("%s", s)
+
+	// Output:
+	// <h1>Cards</h1><div>One: a</div><div>Two: b</div>
+}
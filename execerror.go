@@ -0,0 +1,56 @@
+package gziptemplate
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ExecError is returned when a TagFunc, or a value it writes, fails while a
+// template is being executed. It records the tag at fault and can be
+// detected with errors.As.
+type ExecError struct {
+	// Template is the name of the template within its TemplateSet, or
+	// empty if it is not known.
+	Template string
+
+	// Tag is the name of the tag and Index its index among the tags of
+	// the template, including control tags.
+	Tag   string
+	Index int
+
+	Err error
+}
+
+func (e *ExecError) Error() string {
+	msg := e.Err.Error()
+	if ve, ok := e.Err.(*valueTypeError); ok && ve.tag == e.Tag {
+		msg = ve.detail()
+	}
+
+	if e.Template != "" {
+		return fmt.Sprintf("gziptemplate: template %q: tag=%q (#%d): %s", e.Template, e.Tag, e.Index, msg)
+	}
+
+	return fmt.Sprintf("gziptemplate: tag=%q (#%d): %s", e.Tag, e.Index, msg)
+}
+
+// Unwrap returns the underlying error.
+func (e *ExecError) Unwrap() error {
+	return e.Err
+}
+
+// tagError returns err as an *ExecError for the i'th tag of t, unless it
+// already is one, as when it comes from a nested template.
+func (t *Template) tagError(i int, err error) error {
+	var ee *ExecError
+	if errors.As(err, &ee) {
+		return err
+	}
+
+	return &ExecError{
+		Template: t.name,
+		Tag:      t.tags[i].name,
+		Index:    i,
+		Err:      err,
+	}
+}
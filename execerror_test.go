@@ -0,0 +1,87 @@
+package gziptemplate
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"testing"
+)
+
+func TestExecError(t *testing.T) {
+	errTag := errors.New("tag error")
+	failing := TagFunc(func(w io.Writer, tag string) error { return errTag })
+
+	for _, tc := range []struct {
+		template string
+		m        map[string]interface{}
+		tag      string
+		index    int
+	}{
+		{"a[x]b[y]c", map[string]interface{}{"x": "1", "y": failing}, "y", 1},
+		{"a[#if x]b[#end]c", map[string]interface{}{"x": failingText{errTag}}, "x", 0},
+		{"[#range items]([y])[#end]", map[string]interface{}{
+			"items": []map[string]interface{}{{"y": failing}},
+		}, "y", 1},
+	} {
+		tpl := New(tc.template, "[", "]", BestSpeed)
+		err := tpl.Execute(ioutil.Discard, tc.m)
+
+		var ee *ExecError
+		if !errors.As(err, &ee) {
+			t.Errorf("%q: expected *ExecError, got %#v", tc.template, err)
+			continue
+		}
+		if ee.Tag != tc.tag || ee.Index != tc.index || ee.Template != "" {
+			t.Errorf("%q: unexpected tag %q (#%d) in %q. Expected %q (#%d)",
+				tc.template, ee.Tag, ee.Index, ee.Template, tc.tag, tc.index)
+		}
+		if !errors.Is(err, errTag) {
+			t.Errorf("%q: expected error to wrap %v, got %v", tc.template, errTag, err)
+		}
+	}
+}
+
+// failingText is a condition value that fails, as TagFunc values are not
+// called for conditions.
+type failingText struct{ err error }
+
+func (ft failingText) MarshalText() ([]byte, error) { return nil, ft.err }
+
+func TestExecErrorNested(t *testing.T) {
+	errTag := errors.New("tag error")
+
+	set := NewTemplateSet()
+	if _, err := set.Parse("inner", "<[value]>", "[", "]", BestSpeed); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if _, err := set.Parse("outer", `[a][#include "inner"]`, "[", "]", BestSpeed); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	err := set.ExecuteTemplate(ioutil.Discard, "outer", map[string]interface{}{
+		"value": TagFunc(func(w io.Writer, tag string) error { return errTag }),
+	})
+
+	var ee *ExecError
+	if !errors.As(err, &ee) {
+		t.Fatalf("expected *ExecError, got %#v", err)
+	}
+	if ee.Template != "outer" || ee.Tag != "value" {
+		t.Fatalf("unexpected template %q and tag %q. Expected %q and %q", ee.Template, ee.Tag, "outer", "value")
+	}
+
+	const msg = `gziptemplate: template "outer": tag="value" (#2): tag error`
+	if err.Error() != msg {
+		t.Fatalf("unexpected error %q. Expected %q", err, msg)
+	}
+}
+
+func TestExecErrorUnsupportedValue(t *testing.T) {
+	tpl := New("[#range items][x][#end]", "[", "]", BestSpeed)
+
+	err := tpl.Execute(ioutil.Discard, map[string]interface{}{"items": []int{123}})
+	result := `gziptemplate: tag="items" (#0): contains unexpected value type=[]int{123}`
+	if err == nil || err.Error() != result {
+		t.Fatalf("unexpected error %v. Expected %q", err, result)
+	}
+}
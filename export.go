@@ -0,0 +1,163 @@
+package gziptemplate
+
+import (
+	"bytes"
+	"compress/flate"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// ExportManifestName is the name of the manifest file written by Export.
+const ExportManifestName = "manifest.json"
+
+// ExportManifest describes a template exported by Export.
+//
+// Segments lists the parts of the template in output order. A static
+// segment names a file containing raw deflate data (RFC 1951) that ends
+// byte-aligned with a sync flush and has no final block. A tag segment marks
+// where a substitution value belongs.
+//
+// To render the template, a consumer writes a gzip header (RFC 1952), then
+// for each segment either copies the static file verbatim or writes the tag
+// value as deflate data ending byte-aligned without a final block (stored
+// blocks are the simplest choice). It then writes an empty final block
+// (0x03 0x00) and the gzip trailer, whose CRC-32 is the combination of the
+// static segments' CRC32 with the CRC-32 of each value and whose ISIZE is
+// the total uncompressed length.
+type ExportManifest struct {
+	Version  int             `json:"version"`
+	Level    int             `json:"level"`
+	Segments []ExportSegment `json:"segments"`
+}
+
+// ExportSegment is a single part of an ExportManifest.
+type ExportSegment struct {
+	// Type is either "static" or "tag".
+	Type string `json:"type"`
+
+	// File, Length and CRC32 are set for static segments. File is
+	// relative to the manifest, Length is the uncompressed length and
+	// CRC32 the IEEE CRC-32 of the uncompressed data.
+	File   string `json:"file,omitempty"`
+	Length int    `json:"length,omitempty"`
+	CRC32  uint32 `json:"crc32,omitempty"`
+
+	// Name is the tag name of tag segments.
+	Name string `json:"name,omitempty"`
+}
+
+// Export writes the compiled template to dir, creating it if needed, as a
+// manifest named ExportManifestName and one raw deflate file per static
+// segment.
+//
+// This allows servers not written in Go to perform the same
+// splice-and-substitute rendering with artifacts built by this package. See
+// ExportManifest for the format.
+//
+// Templates with sections resolved at execution time, or with tags whose
+// values are escaped or filtered, cannot be exported as the manifest has no
+// way to describe them.
+func (t *Template) Export(dir string) error {
+	for _, tag := range t.tags {
+		if tag.op != opValue && tag.op != opNop && tag.op != opBlock {
+			return errors.New("gziptemplate: cannot export template with sections resolved at execution time")
+		}
+		if tag.escape != nil || tag.filterURL {
+			return fmt.Errorf("gziptemplate: cannot export tag=%q with escaping or filters", tag.name)
+		}
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	manifest := ExportManifest{
+		Version: 1,
+		Level:   t.level,
+	}
+
+	segs, err := t.rawSegments()
+	if err != nil {
+		return err
+	}
+
+	for i, seg := range segs {
+		name := fmt.Sprintf("%d.deflate", i)
+		if err := ioutil.WriteFile(filepath.Join(dir, name), seg.b, 0644); err != nil {
+			return err
+		}
+
+		manifest.Segments = append(manifest.Segments, ExportSegment{
+			Type:   "static",
+			File:   name,
+			Length: int(t.textLens[i]),
+			CRC32:  seg.crc,
+		})
+
+		if i < len(t.tags) && t.tags[i].op == opValue {
+			manifest.Segments = append(manifest.Segments, ExportSegment{
+				Type: "tag",
+				Name: t.tags[i].name,
+			})
+		}
+	}
+
+	b, err := json.MarshalIndent(&manifest, "", "\t")
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(filepath.Join(dir, ExportManifestName), b, 0644)
+}
+
+// rawSegment is a static segment of a template as raw deflate data that
+// ends byte-aligned with a sync flush and has no final block, along with
+// the CRC-32 of its uncompressed text.
+type rawSegment struct {
+	b   []byte
+	crc uint32
+}
+
+// rawSegments returns the static segments of t as raw deflate data, each
+// compressed independently. They are compressed on first use.
+func (t *Template) rawSegments() ([]rawSegment, error) {
+	t.rawOnce.Do(func() {
+		texts, err := t.plainTexts()
+		if err != nil {
+			t.rawErr = err
+			return
+		}
+
+		var buf bytes.Buffer
+		fw, err := flate.NewWriter(&buf, t.level)
+		if err != nil {
+			t.rawErr = err
+			return
+		}
+
+		segs := make([]rawSegment, len(texts))
+		for i, text := range texts {
+			buf = bytes.Buffer{}
+			fw.Reset(&buf)
+			if _, err := fw.Write(text); err != nil {
+				t.rawErr = err
+				return
+			}
+			if err := fw.Flush(); err != nil {
+				t.rawErr = err
+				return
+			}
+
+			segs[i] = rawSegment{buf.Bytes(), crc32.ChecksumIEEE(text)}
+		}
+
+		t.raw = segs
+	})
+
+	return t.raw, t.rawErr
+}
@@ -0,0 +1,86 @@
+package gziptemplate
+
+import (
+	"bytes"
+	"compress/flate"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestExport(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gziptemplate")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	tpl := New("foo[foo]bar[bar]baz", "[", "]", BestCompression)
+	if err := tpl.Export(dir); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	b, err := ioutil.ReadFile(filepath.Join(dir, ExportManifestName))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var manifest ExportManifest
+	if err := json.Unmarshal(b, &manifest); err != nil {
+		t.Fatalf("invalid manifest: %s", err)
+	}
+
+	if manifest.Level != BestCompression {
+		t.Fatalf("unexpected level %d. Expected %d", manifest.Level, BestCompression)
+	}
+
+	// Splice the segments together with the tag names as values.
+	var deflated bytes.Buffer
+	for _, seg := range manifest.Segments {
+		switch seg.Type {
+		case "static":
+			b, err := ioutil.ReadFile(filepath.Join(dir, seg.File))
+			if err != nil {
+				t.Fatal(err)
+			}
+			deflated.Write(b)
+		case "tag":
+			fw, _ := flate.NewWriter(&deflated, NoCompression)
+			fw.Write([]byte(seg.Name))
+			fw.Flush()
+		default:
+			t.Fatalf("unexpected segment type %q", seg.Type)
+		}
+	}
+	deflated.Write([]byte{0x03, 0x00})
+
+	s, err := ioutil.ReadAll(flate.NewReader(&deflated))
+	if err != nil {
+		t.Fatalf("invalid deflate data: %s", err)
+	}
+
+	result := "foofoobarbarbaz"
+	if string(s) != result {
+		t.Fatalf("unexpected template value %q. Expected %q", s, result)
+	}
+}
+
+func TestExportEscaping(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gziptemplate")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	for _, tpl := range []*Template{
+		New("foo[foo|html]bar", "[", "]", BestCompression),
+		New("foo[foo]bar", "[", "]", BestCompression, WithEscaping(map[string]Escaping{"foo": EscapeHTML})),
+		New("<p>[foo]</p>", "[", "]", BestCompression, WithAutoEscape()),
+	} {
+		if err := tpl.Export(dir); err == nil {
+			t.Errorf("%q: expected error", tpl.Source())
+		}
+	}
+}
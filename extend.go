@@ -0,0 +1,69 @@
+package gziptemplate
+
+import "go.tmthrgd.dev/gzipbuilder"
+
+// extend returns a copy of base with its blocks replaced by the templates in
+// defines, keyed by block name. The segments of both are shared rather than
+// compressed again.
+//
+// The copy keeps the defaults and registered values of base. Its other
+// settings are replaced by those the extending template is parsed with, so
+// the name and pooled writers of base are not kept.
+func extend(base *Template, defines map[string]*Template) (*Template, error) {
+	t := base.cloneSettings(nil)
+	t.name, t.smallest = "", nil
+	t.texts = []*gzipbuilder.PrecompressedData{base.texts[0]}
+	t.textLens = []int64{base.textLens[0]}
+
+	// index maps the tags of base to their index in t and fromBase
+	// reports which tags of t came from base, so that jumps may be
+	// adjusted.
+	index := make([]int, len(base.tags))
+	var fromBase []bool
+
+	add := func(tag tag, isBase bool) {
+		tag.pos = -1
+		t.tags = append(t.tags, tag)
+		fromBase = append(fromBase, isBase)
+	}
+
+	for i := 0; i < len(base.tags); i++ {
+		tag := base.tags[i]
+		index[i] = len(t.tags)
+		add(tag, true)
+
+		def, ok := defines[tag.name]
+		if tag.op != opBlock || !ok {
+			t.texts = append(t.texts, base.texts[i+1])
+			t.textLens = append(t.textLens, base.textLens[i+1])
+			continue
+		}
+
+		off := len(t.tags)
+		for _, tag := range def.tags {
+			tag.jump += off
+			add(tag, false)
+		}
+		t.texts = append(t.texts, def.texts...)
+		t.textLens = append(t.textLens, def.textLens...)
+
+		// Skip the contents of the block, resuming with the tag that
+		// ends it.
+		i = tag.jump - 1
+	}
+
+	for i := range t.tags {
+		if fromBase[i] && t.tags[i].op != opValue {
+			t.tags[i].jump = index[t.tags[i].jump]
+		}
+	}
+
+	if len(t.tags) == 0 {
+		var err error
+		if t.template, err = gzipSegment(t.level, t.texts[0]); err != nil {
+			return nil, err
+		}
+	}
+
+	return t, nil
+}
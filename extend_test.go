@@ -0,0 +1,92 @@
+package gziptemplate
+
+import "testing"
+
+func TestExtends(t *testing.T) {
+	set := NewTemplateSet()
+	base, err := set.Parse("base", "<title>[#block title]Default[#end]</title>"+
+		"[#if user]<nav>[user]</nav>[#block nav][#end][#end]"+
+		"<main>[#block main]<p>[body]</p>[#end]</main>"+
+		"[#block footer]<footer>[#block copyright](c)[#end]</footer>[#end]", "[", "]", BestCompression)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	child, err := set.Parse("child", "ignored [#extends \"base\"]\n"+
+		"[#define title][title] - Site[#end]\n"+
+		"[#define nav]<a>[#if admin]admin[#else]home[#end]</a>[#end]\n"+
+		"[#define copyright](c) [year][#end]", "[", "]", BestCompression)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	m := map[string]interface{}{
+		"title": "Page",
+		"user":  "bob",
+		"body":  "Body",
+		"year":  "2019",
+	}
+
+	s := decompressBytes(t, base.ExecuteBytes(m))
+	result := "<title>Default</title><nav>bob</nav><main><p>Body</p></main><footer>(c)</footer>"
+	if string(s) != result {
+		t.Fatalf("unexpected template value %q. Expected %q", s, result)
+	}
+
+	s = decompressBytes(t, child.ExecuteBytes(m))
+	result = "<title>Page - Site</title><nav>bob</nav><a>home</a><main><p>Body</p></main><footer>(c) 2019</footer>"
+	if string(s) != result {
+		t.Fatalf("unexpected template value %q. Expected %q", s, result)
+	}
+
+	delete(m, "user")
+	m["admin"] = true
+	s = decompressBytes(t, child.ExecuteBytes(m))
+	result = "<title>Page - Site</title><main><p>Body</p></main><footer>(c) 2019</footer>"
+	if string(s) != result {
+		t.Fatalf("unexpected template value %q. Expected %q", s, result)
+	}
+}
+
+func TestExtendsErrors(t *testing.T) {
+	set := NewTemplateSet()
+	if _, err := set.Parse("base", "[#block a]a[#end]", "[", "]", BestCompression); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	for _, template := range []string{
+		`[#extends "missing"]`,
+		`[#extends base]`,
+		`[#define a]x[#end]`,
+		`[#extends "base"][#extends "base"]`,
+		`[#extends "base"][#define a]x`,
+		`[#extends "base"][#define a]x[#end][#define a]y[#end]`,
+		`[#extends "base"][#define a][#define b][#end][#end]`,
+		`[#block a]x[#else]y[#end]`,
+		`[#block]x[#end]`,
+	} {
+		if _, err := set.Parse("child", template, "[", "]", BestCompression); err == nil {
+			t.Fatalf("expected non-nil error for %q. got nil", template)
+		}
+	}
+}
+
+func TestExtendsKeepsDefaults(t *testing.T) {
+	set := NewTemplateSet()
+	base, err := set.Parse("base", "<main>[#block main][body][#end]</main>", "[", "]", BestCompression)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	base.SetDefault("body", "empty")
+
+	child, err := set.Parse("child", `[#extends "base"][#define main]<p>[body]</p>[#end]`, "[", "]", BestCompression)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	s := decompressBytes(t, child.ExecuteBytes(nil))
+	result := "<main><p>empty</p></main>"
+	if string(s) != result {
+		t.Fatalf("unexpected template value %q. Expected %q", s, result)
+	}
+}
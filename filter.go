@@ -0,0 +1,74 @@
+package gziptemplate
+
+import "io"
+
+// Filter transforms a tag value, writing the result of transforming p to w.
+// A value may be passed to a Filter in several chunks, so a Filter must not
+// depend on seeing the whole value at once.
+//
+// The following filters are built in:
+//   * html, htmlescape - escapes for use as HTML text
+//   * urlquery, urlescape - escapes for use as URL query components
+//   * js, jsescape - escapes for use within JavaScript string literals
+//   * json, jsonescape - escapes for use within JSON string literals
+//
+// Additional filters may be supplied with WithFilters.
+type Filter func(w io.Writer, p []byte) error
+
+var builtinFilters = map[string]escapeFunc{
+	"html":       escapeHTML,
+	"htmlescape": escapeHTML,
+	"urlquery":   escapeURL,
+	"urlescape":  escapeURL,
+	"js":         escapeJS,
+	"jsescape":   escapeJS,
+	"json":       escapeJSON,
+	"jsonescape": escapeJSON,
+}
+
+// escapeJSON escapes p as encoding/json does the contents of a string, but a
+// byte at a time. Bytes outside of ASCII are written as they are, so that
+// runes split across writes are not corrupted.
+func escapeJSON(w io.Writer, p []byte) error {
+	const hex = "0123456789abcdef"
+
+	b := make([]byte, 0, len(p))
+	for _, c := range p {
+		switch {
+		case c == '"' || c == '\\':
+			b = append(b, '\\', c)
+		case c == '\n':
+			b = append(b, '\\', 'n')
+		case c == '\r':
+			b = append(b, '\\', 'r')
+		case c == '\t':
+			b = append(b, '\\', 't')
+		case c < 0x20 || c == '<' || c == '>' || c == '&':
+			b = append(b, '\\', 'u', '0', '0', hex[c>>4], hex[c&0xf])
+		default:
+			b = append(b, c)
+		}
+	}
+
+	_, err := w.Write(b)
+	return err
+}
+
+// chainEscape returns an escapeFunc that applies each non-nil fns in turn.
+func chainEscape(fns ...escapeFunc) escapeFunc {
+	var chain escapeFunc
+	for i := len(fns) - 1; i >= 0; i-- {
+		fn := fns[i]
+		switch {
+		case fn == nil:
+		case chain == nil:
+			chain = fn
+		default:
+			next := chain
+			chain = func(w io.Writer, p []byte) error {
+				return fn(&escapeWriter{w: w, escape: next}, p)
+			}
+		}
+	}
+	return chain
+}
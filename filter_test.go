@@ -0,0 +1,50 @@
+package gziptemplate
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+func TestFilters(t *testing.T) {
+	template := `<a href="/?q=[ref|urlquery]">[ref|html]</a>` +
+		`<script>var s = "[ref | json]";</script>` +
+		`[missing|upper|html|<default|text>][ref|upper|urlescape][ref|unknown]`
+	tpl := New(template, "[", "]", BestCompression, WithFilters(map[string]Filter{
+		"upper": func(w io.Writer, p []byte) error {
+			_, err := w.Write(bytes.ToUpper(p))
+			return err
+		},
+	}), WithEscaping(map[string]Escaping{
+		"missing": EscapeHTML,
+	}))
+
+	s := decompressBytes(t, tpl.ExecuteBytes(map[string]interface{}{
+		"ref":     `a&b "c"`,
+		"unknown": "x",
+	}))
+	result := `<a href="/?q=a%26b+%22c%22">a&amp;b &#34;c&#34;</a>` +
+		`<script>var s = "a\u0026b \"c\"";</script>` +
+		`<default|text>A%26B+%22C%22a&b "c"`
+	if string(s) != result {
+		t.Fatalf("unexpected template value %q. Expected %q", s, result)
+	}
+}
+
+func TestFilterJSONSplitRune(t *testing.T) {
+	tpl := New(`"[v|json]"`, "[", "]", BestCompression)
+
+	s := decompressBytes(t, tpl.ExecuteFuncBytes(func(w io.Writer, tag string) error {
+		// Split é across two writes.
+		for _, p := range []string{"h\xc3", "\xa9llo\n<\x01>"} {
+			if _, err := io.WriteString(w, p); err != nil {
+				return err
+			}
+		}
+		return nil
+	}))
+	result := `"héllo\n\u003c\u0001\u003e"`
+	if string(s) != result {
+		t.Fatalf("unexpected template value %q. Expected %q", s, result)
+	}
+}
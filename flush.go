@@ -0,0 +1,80 @@
+package gziptemplate
+
+import (
+	"io"
+
+	"go.tmthrgd.dev/gzipbuilder"
+)
+
+// ExecuteFuncFlush is like ExecuteFunc but flushes w after each static
+// segment is written, so that a partially rendered page reaches the client
+// while the values that follow are being produced. w is flushed if it has a
+// Flush method, as http.ResponseWriter and bufio.Writer do.
+//
+// Static segments end at each tag and at each flush directive, written with
+// "[" and "]" as delimiters as [#flush]. The directive allows, for example,
+// the head of a page to be sent before a slow body is rendered.
+func (t *Template) ExecuteFuncFlush(w io.Writer, f TagFunc) error {
+	flush := flushFunc(w)
+	if flush == nil || len(t.tags) == 0 || t.patch != nil {
+		if err := t.ExecuteFunc(w, f); err != nil {
+			return err
+		}
+
+		if flush != nil {
+			return flush()
+		}
+		return nil
+	}
+
+	t.stats.executed()
+
+	var flushErr error
+	lw, ol := t.limitOutput(w)
+	gw := gzipbuilder.NewWriter(lw, t.level)
+	s := stream{
+		add: func(d *gzipbuilder.PrecompressedData) {
+			gw.AddPrecompressedData(d)
+			if flushErr == nil {
+				flushErr = flush()
+			}
+		},
+		w: gw.UncompressedWriter(),
+	}.withLimit(ol)
+
+	if err := t.execute(s, f); err != nil {
+		return err
+	}
+	if flushErr != nil {
+		return flushErr
+	}
+
+	if err := gw.Close(); err != nil {
+		return err
+	}
+	return flush()
+}
+
+// ExecuteFlush is like Execute but flushes w after each static segment is
+// written.
+//
+// See ExecuteFuncFlush for details.
+func (t *Template) ExecuteFlush(w io.Writer, m map[string]interface{}) error {
+	return t.ExecuteFuncFlush(w, t.mapTagFunc(m))
+}
+
+// flushFunc returns a function that flushes w, or nil if w can't be
+// flushed.
+func flushFunc(w io.Writer) func() error {
+	switch f := w.(type) {
+	case interface{ Flush() error }:
+		return f.Flush
+	case interface{ Flush() }:
+		return func() error {
+			f.Flush()
+			return nil
+		}
+	default:
+		return nil
+	}
+}
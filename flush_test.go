@@ -0,0 +1,50 @@
+package gziptemplate
+
+import (
+	"bytes"
+	"testing"
+)
+
+type flushRecorder struct {
+	bytes.Buffer
+	flushes int
+}
+
+func (fr *flushRecorder) Flush() {
+	fr.flushes++
+}
+
+func TestExecuteFlush(t *testing.T) {
+	tpl := New("<head>[title]</head>[#flush]<body>[body]</body>", "[", "]", BestCompression)
+
+	var fr flushRecorder
+	if err := tpl.ExecuteFlush(&fr, map[string]interface{}{"title": "t", "body": "b"}); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	result := "<head>t</head><body>b</body>"
+	if s := decompressBytes(t, fr.Bytes()); string(s) != result {
+		t.Fatalf("unexpected template value %q. Expected %q", s, result)
+	}
+
+	// One flush for each of the four segments and one on completion.
+	if fr.flushes != 5 {
+		t.Fatalf("unexpected number of flushes %d. Expected %d", fr.flushes, 5)
+	}
+
+	fr = flushRecorder{}
+	if err := New("static", "[", "]", BestCompression).ExecuteFlush(&fr, nil); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if fr.flushes != 1 {
+		t.Fatalf("unexpected number of flushes %d. Expected %d", fr.flushes, 1)
+	}
+
+	var buf bytes.Buffer
+	if err := tpl.ExecuteFlush(&buf, map[string]interface{}{"title": "t"}); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if s := decompressBytes(t, buf.Bytes()); string(s) != "<head>t</head><body></body>" {
+		t.Fatalf("unexpected template value %q. Expected %q", s, "<head>t</head><body></body>")
+	}
+}
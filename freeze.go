@@ -0,0 +1,53 @@
+package gziptemplate
+
+import "io"
+
+// Freeze substitutes template tags (placeholders) with the corresponding
+// values from the map m and returns the gzipped result.
+//
+// It is intended for templates whose values change rarely, such as only on
+// deploy: the result may be cached and served repeatedly, or wrapped with
+// NewFrozenTemplate.
+func (t *Template) Freeze(m map[string]interface{}) ([]byte, error) {
+	return t.ExecuteBytesErr(m)
+}
+
+// FrozenTemplate is a template with all of its values already substituted.
+// It has the execution methods of Template, which ignore their arguments
+// and write the same gzipped output each time.
+type FrozenTemplate struct {
+	b []byte
+}
+
+// NewFrozenTemplate returns a FrozenTemplate with the gzipped output b, as
+// returned by Template.Freeze. b must not be modified afterwards.
+func NewFrozenTemplate(b []byte) *FrozenTemplate {
+	return &FrozenTemplate{b}
+}
+
+// Bytes returns the gzipped output of ft. It must not be modified.
+func (ft *FrozenTemplate) Bytes() []byte {
+	return ft.b
+}
+
+// ExecuteFunc writes the gzipped output of ft to w. f is never called.
+func (ft *FrozenTemplate) ExecuteFunc(w io.Writer, f TagFunc) error {
+	_, err := w.Write(ft.b)
+	return err
+}
+
+// Execute writes the gzipped output of ft to w. m is ignored.
+func (ft *FrozenTemplate) Execute(w io.Writer, m map[string]interface{}) error {
+	return ft.ExecuteFunc(w, nil)
+}
+
+// ExecuteFuncBytes returns a copy of the gzipped output of ft. f is never
+// called.
+func (ft *FrozenTemplate) ExecuteFuncBytes(f TagFunc) []byte {
+	return append([]byte(nil), ft.b...)
+}
+
+// ExecuteBytes returns a copy of the gzipped output of ft. m is ignored.
+func (ft *FrozenTemplate) ExecuteBytes(m map[string]interface{}) []byte {
+	return ft.ExecuteFuncBytes(nil)
+}
@@ -0,0 +1,41 @@
+package gziptemplate
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestFreeze(t *testing.T) {
+	tpl := New("foo[bar]baz", "[", "]", BestCompression)
+
+	b, err := tpl.Freeze(map[string]interface{}{"bar": "111"})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if s := decompressBytes(t, b); string(s) != "foo111baz" {
+		t.Fatalf("unexpected template value %q. Expected %q", s, "foo111baz")
+	}
+
+	if _, err := tpl.Freeze(map[string]interface{}{"bar": struct{}{}}); err == nil {
+		t.Fatal("expected error for unsupported value type")
+	}
+
+	ft := NewFrozenTemplate(b)
+
+	var buf bytes.Buffer
+	if err := ft.Execute(&buf, map[string]interface{}{"bar": "222"}); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if !bytes.Equal(buf.Bytes(), b) {
+		t.Fatalf("unexpected output %q. Expected %q", buf.Bytes(), b)
+	}
+
+	out := ft.ExecuteBytes(nil)
+	if !bytes.Equal(out, b) {
+		t.Fatalf("unexpected output %q. Expected %q", out, b)
+	}
+	out[0] ^= 0xff
+	if !bytes.Equal(ft.Bytes(), b) {
+		t.Fatal("ExecuteBytes returned the frozen output rather than a copy")
+	}
+}
@@ -1,6 +1,6 @@
 module go.tmthrgd.dev/gziptemplate
 
-go 1.12
+go 1.16
 
 require (
 	github.com/tmthrgd/fasttemplate v0.0.0-20190303111627-606b8ff2d0e2
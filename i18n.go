@@ -0,0 +1,71 @@
+package gziptemplate
+
+import (
+	"io"
+	"strings"
+)
+
+// messagePrefix begins the name of message tags.
+const messagePrefix = "t:"
+
+// Translator supplies the messages substituted for message tags, written with
+// "[" and "]" as delimiters as [t:key].
+type Translator interface {
+	// Translate returns the message for key in locale and whether it
+	// exists.
+	Translate(locale, key string) (string, bool)
+}
+
+// Messages is a Translator backed by a map of locales to the messages of
+// each, by key.
+type Messages map[string]map[string]string
+
+// Translate implements Translator.
+func (m Messages) Translate(locale, key string) (string, bool) {
+	msg, ok := m[locale][key]
+	return msg, ok
+}
+
+// Translate returns a Middleware that substitutes message tags with the
+// messages of tr for locale. Other tags, and message tags tr has no message
+// for, are passed to the next TagFunc.
+//
+// Translating each execution wastes effort on messages that are constant for
+// a locale, see Localize.
+func Translate(tr Translator, locale string) Middleware {
+	return func(next TagFunc) TagFunc {
+		return func(w io.Writer, tag string) error {
+			if !strings.HasPrefix(tag, messagePrefix) {
+				return next(w, tag)
+			}
+
+			msg, ok := tr.Translate(locale, tag[len(messagePrefix):])
+			if !ok {
+				return next(w, tag)
+			}
+
+			return writeValue(w, tag, msg)
+		}
+	}
+}
+
+// Localize returns a copy of t with its message tags substituted with the
+// messages of tr for locale, as with Derive, so that they are precompressed
+// along with the static text. Message tags tr has no message for remain
+// dynamic.
+//
+// A template may be localized once for each locale it is served in.
+func (t *Template) Localize(tr Translator, locale string) (*Template, error) {
+	m := make(map[string]interface{})
+	for _, tag := range t.tags {
+		if tag.op != opValue || !strings.HasPrefix(tag.name, messagePrefix) {
+			continue
+		}
+
+		if msg, ok := tr.Translate(locale, tag.name[len(messagePrefix):]); ok {
+			m[tag.name] = msg
+		}
+	}
+
+	return t.Derive(m)
+}
@@ -0,0 +1,66 @@
+package gziptemplate
+
+import "testing"
+
+var testMessages = Messages{
+	"en": {"welcome": "Welcome", "bye": "Goodbye"},
+	"de": {"welcome": "Willkommen"},
+}
+
+func TestTranslate(t *testing.T) {
+	tpl := New("[t:welcome], [name]! [t:bye]", "[", "]", BestCompression)
+
+	f := Chain(tpl.mapTagFunc(map[string]interface{}{
+		"name":  "Bob",
+		"t:bye": "bye",
+	}), Translate(testMessages, "de"))
+
+	s := decompressBytes(t, tpl.ExecuteFuncBytes(f))
+	result := "Willkommen, Bob! bye"
+	if string(s) != result {
+		t.Fatalf("unexpected template value %q. Expected %q", s, result)
+	}
+}
+
+func TestLocalize(t *testing.T) {
+	tpl := New("<h1>[t:welcome|html], [name]!</h1><p>[t:bye]</p>", "[", "]", BestCompression)
+
+	for _, test := range []struct {
+		locale, result string
+		tags           int
+	}{
+		{"en", "<h1>Welcome, Bob!</h1><p>Goodbye</p>", 1},
+		{"de", "<h1>Willkommen, Bob!</h1><p>Auf Wiedersehen</p>", 2},
+	} {
+		lt, err := tpl.Localize(testMessages, test.locale)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if len(lt.tags) != test.tags {
+			t.Fatalf("unexpected number of tags %d for %s. Expected %d", len(lt.tags), test.locale, test.tags)
+		}
+
+		s := decompressBytes(t, lt.ExecuteBytes(map[string]interface{}{
+			"name":  "Bob",
+			"t:bye": "Auf Wiedersehen",
+		}))
+		if string(s) != test.result {
+			t.Fatalf("unexpected template value %q. Expected %q", s, test.result)
+		}
+	}
+}
+
+func TestLocalizeFallback(t *testing.T) {
+	tpl := New("[t:welcome|Hi] [t:missing|Bye]", "[", "]", BestCompression)
+
+	lt, err := tpl.Localize(testMessages, "de")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	s := decompressBytes(t, lt.ExecuteBytes(nil))
+	result := "Willkommen Bye"
+	if string(s) != result {
+		t.Fatalf("unexpected template value %q. Expected %q", s, result)
+	}
+}
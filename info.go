@@ -0,0 +1,72 @@
+package gziptemplate
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+)
+
+// StreamInfo describes the gzip stream written by an execution.
+type StreamInfo struct {
+	// CompressedLen is the length of the gzip stream.
+	CompressedLen int64
+
+	// UncompressedLen is the length of the output once decompressed,
+	// modulo 2^32 as recorded in the gzip trailer.
+	UncompressedLen int64
+
+	// CRC32 is the IEEE CRC-32 of the decompressed output.
+	CRC32 uint32
+}
+
+// ExecuteFuncInfo is like ExecuteFunc but also returns a StreamInfo
+// describing the output, such as to populate object store metadata or HTTP
+// headers. It is taken from the gzip trailer, so the output is never
+// decompressed.
+func (t *Template) ExecuteFuncInfo(w io.Writer, f TagFunc) (StreamInfo, error) {
+	iw := infoWriter{w: w}
+	if err := t.ExecuteFunc(&iw, f); err != nil {
+		return StreamInfo{}, err
+	}
+
+	if iw.n < int64(len(iw.tail)) {
+		return StreamInfo{}, errors.New("gziptemplate: gzip stream too short")
+	}
+
+	return StreamInfo{
+		CompressedLen:   iw.n,
+		UncompressedLen: int64(binary.LittleEndian.Uint32(iw.tail[4:])),
+		CRC32:           binary.LittleEndian.Uint32(iw.tail[:4]),
+	}, nil
+}
+
+// ExecuteInfo is like Execute but also returns a StreamInfo describing the
+// output.
+//
+// See ExecuteFuncInfo for details.
+func (t *Template) ExecuteInfo(w io.Writer, m map[string]interface{}) (StreamInfo, error) {
+	return t.ExecuteFuncInfo(w, t.mapTagFunc(m))
+}
+
+// infoWriter counts the bytes written to w and keeps the last eight, which
+// form the gzip trailer once the stream is complete.
+type infoWriter struct {
+	w    io.Writer
+	n    int64
+	tail [8]byte
+}
+
+func (iw *infoWriter) Write(p []byte) (int, error) {
+	n, err := iw.w.Write(p)
+	iw.n += int64(n)
+
+	p = p[:n]
+	if len(p) >= len(iw.tail) {
+		copy(iw.tail[:], p[len(p)-len(iw.tail):])
+	} else {
+		copy(iw.tail[:], iw.tail[len(p):])
+		copy(iw.tail[len(iw.tail)-len(p):], p)
+	}
+
+	return n, err
+}
@@ -0,0 +1,46 @@
+package gziptemplate
+
+import (
+	"bytes"
+	"hash/crc32"
+	"testing"
+)
+
+func TestExecuteInfo(t *testing.T) {
+	tpl := New("foo[bar]baz", "[", "]", BestCompression)
+
+	var buf bytes.Buffer
+	info, err := tpl.ExecuteInfo(&buf, map[string]interface{}{"bar": "111"})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	s := decompressBytes(t, buf.Bytes())
+	expect := StreamInfo{
+		CompressedLen:   int64(buf.Len()),
+		UncompressedLen: int64(len(s)),
+		CRC32:           crc32.ChecksumIEEE(s),
+	}
+	if info != expect {
+		t.Fatalf("unexpected info %+v. Expected %+v", info, expect)
+	}
+}
+
+func TestInfoWriterSmallWrites(t *testing.T) {
+	p := []byte("0123456789abcdef")
+
+	var buf bytes.Buffer
+	iw := infoWriter{w: &buf}
+	for i := range p {
+		if _, err := iw.Write(p[i : i+1]); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	}
+
+	if iw.n != int64(len(p)) {
+		t.Fatalf("unexpected length %d. Expected %d", iw.n, len(p))
+	}
+	if !bytes.Equal(iw.tail[:], p[len(p)-8:]) {
+		t.Fatalf("unexpected tail %q. Expected %q", iw.tail, p[len(p)-8:])
+	}
+}
@@ -0,0 +1,61 @@
+package gziptemplate
+
+// Tags returns the names of the tags of t in order of first occurrence. Each
+// name is returned once, and includes those of conditional and repeated
+// sections resolved at execution time.
+//
+// Names within repeated sections are included, although their values may be
+// given by the items rather than the map passed to Execute.
+func (t *Template) Tags() []string {
+	var names []string
+	seen := make(map[string]bool, len(t.tags))
+	for _, tag := range t.tags {
+		switch tag.op {
+		case opValue, opIf, opRange:
+		default:
+			continue
+		}
+
+		if !seen[tag.name] {
+			seen[tag.name] = true
+			names = append(names, tag.name)
+		}
+	}
+
+	return names
+}
+
+// NumSegments returns the number of static segments of t. There is always
+// one more segment than there are tags, though segments may be empty.
+func (t *Template) NumSegments() int {
+	return len(t.texts)
+}
+
+// Segment describes a static segment of a template.
+type Segment struct {
+	// Size is the uncompressed size of the segment in bytes.
+	Size int64
+
+	// CompressedSize is the size of the precompressed segment in bytes,
+	// as written to the output of Execute.
+	CompressedSize int64
+}
+
+// Segments returns the sizes of each static segment of t. Together with
+// NumSegments, this allows the memory held by templates to be estimated.
+func (t *Template) Segments() ([]Segment, error) {
+	sizes, _, err := t.segmentSizes()
+	if err != nil {
+		return nil, err
+	}
+
+	segs := make([]Segment, len(t.texts))
+	for i := range segs {
+		segs[i] = Segment{
+			Size:           t.textLens[i],
+			CompressedSize: sizes[i],
+		}
+	}
+
+	return segs, nil
+}
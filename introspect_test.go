@@ -0,0 +1,45 @@
+package gziptemplate
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTags(t *testing.T) {
+	tpl := New("[foo] [#if cond][bar][#end] [#range items][baz][#end] [foo] [qux|default]", "[", "]", BestCompression)
+
+	tags := tpl.Tags()
+	expect := []string{"foo", "cond", "bar", "items", "baz", "qux"}
+	if !reflect.DeepEqual(tags, expect) {
+		t.Fatalf("unexpected tags %q. Expected %q", tags, expect)
+	}
+
+	if tags := New("static", "[", "]", BestCompression).Tags(); len(tags) != 0 {
+		t.Fatalf("unexpected tags %q. Expected none", tags)
+	}
+}
+
+func TestSegments(t *testing.T) {
+	tpl := New("hello [name], welcome to [place]!", "[", "]", BestCompression)
+
+	if n := tpl.NumSegments(); n != 3 {
+		t.Fatalf("unexpected number of segments %d. Expected 3", n)
+	}
+
+	segs, err := tpl.Segments()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(segs) != 3 {
+		t.Fatalf("unexpected number of segments %d. Expected 3", len(segs))
+	}
+
+	for i, size := range []int64{6, 13, 1} {
+		if segs[i].Size != size {
+			t.Errorf("unexpected size %d of segment %d. Expected %d", segs[i].Size, i, size)
+		}
+		if segs[i].CompressedSize <= 0 {
+			t.Errorf("unexpected compressed size %d of segment %d", segs[i].CompressedSize, i)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package gziptemplate
+
+import "io"
+
+// defaultSeparator separates the elements of slice values unless another
+// separator is given with WithSeparator or Joined.
+const defaultSeparator = ","
+
+// Joined is a substitution value whose elements are written separated by
+// Sep. It may be used as a value in the map passed to Execute*. Value must
+// be a []string or a [][]byte.
+//
+// A []string or [][]byte given directly as a value is separated by the
+// template's separator, see WithSeparator.
+type Joined struct {
+	Value interface{}
+	Sep   string
+}
+
+// writeTo writes j for tag to w.
+func (j Joined) writeTo(w io.Writer, tag string) error {
+	switch value := j.Value.(type) {
+	case []string:
+		for i, s := range value {
+			if i > 0 {
+				if _, err := io.WriteString(w, j.Sep); err != nil {
+					return err
+				}
+			}
+			if _, err := io.WriteString(w, s); err != nil {
+				return err
+			}
+		}
+		return nil
+	case [][]byte:
+		for i, b := range value {
+			if i > 0 {
+				if _, err := io.WriteString(w, j.Sep); err != nil {
+					return err
+				}
+			}
+			if _, err := w.Write(b); err != nil {
+				return err
+			}
+		}
+		return nil
+	default:
+		return unsupportedValue(tag, j.Value)
+	}
+}
+
+// templateValue returns v, the value for tag, with the values registered
+// with RegisterValues and the separator given by WithSeparator applied.
+func (t *Template) templateValue(tag string, v interface{}) interface{} {
+	switch v.(type) {
+	case []string, [][]byte:
+		if t.separator != nil {
+			return Joined{v, *t.separator}
+		}
+		return v
+	default:
+		return t.registeredValue(tag, v)
+	}
+}
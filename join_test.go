@@ -0,0 +1,34 @@
+package gziptemplate
+
+import "testing"
+
+func TestSliceValues(t *testing.T) {
+	m := map[string]interface{}{
+		"s":      []string{"a", "b", "c"},
+		"b":      [][]byte{[]byte("x"), []byte("y")},
+		"j":      Joined{[]string{"1", "2"}, " | "},
+		"empty":  []string{},
+		"single": [][]byte{[]byte("only")},
+	}
+
+	for _, test := range []struct {
+		opts   []Option
+		result string
+	}{
+		{nil, "a,b,c x,y 1 | 2 () only"},
+		{[]Option{WithSeparator(", ")}, "a, b, c x, y 1 | 2 () only"},
+		{[]Option{WithSeparator("")}, "abc xy 1 | 2 () only"},
+	} {
+		tpl := New("[s] [b] [j] ([#if empty]x[#end]) [single]", "[", "]", BestCompression, test.opts...)
+
+		s := decompressBytes(t, tpl.ExecuteBytes(m))
+		if string(s) != test.result {
+			t.Fatalf("unexpected template value %q. Expected %q", s, test.result)
+		}
+	}
+
+	tpl := New("[j]", "[", "]", BestCompression)
+	if _, err := tpl.ExecuteBytesErr(map[string]interface{}{"j": Joined{"a", ","}}); err == nil {
+		t.Fatal("expected error for unsupported value type")
+	}
+}
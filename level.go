@@ -0,0 +1,80 @@
+package gziptemplate
+
+import (
+	"fmt"
+
+	"go.tmthrgd.dev/gzipbuilder"
+)
+
+// WithLevel returns a copy of t with its static segments compressed again at
+// the given compression level, without parsing the template source again.
+//
+// This allows, for instance, a template to be rendered with BestCompression
+// for cached responses and BestSpeed for those rendered on each request.
+func (t *Template) WithLevel(level int) (*Template, error) {
+	if level < HuffmanOnly || level > BestCompression {
+		return nil, fmt.Errorf("gziptemplate: invalid compression level: %d", level)
+	}
+
+	return t.recompress(level, nil)
+}
+
+// recompress returns a copy of t with its static segments passed through
+// transform, if non-nil, and compressed again at level.
+func (t *Template) recompress(level int, transform func([]byte) []byte) (*Template, error) {
+	plain, err := t.plainTexts()
+	if err != nil {
+		return nil, err
+	}
+
+	nt := t.cloneSettings(nil)
+	nt.level = level
+	nt.tags = t.tags
+	nt.source = t.source
+	nt.texts = make([]*gzipbuilder.PrecompressedData, len(plain))
+	nt.textLens = t.textLens
+	nt.textPos = t.textPos
+
+	if transform != nil {
+		nt.textLens = make([]int64, len(plain))
+	}
+
+	w := gzipbuilder.NewPrecompressedWriter(level)
+	for i, text := range plain {
+		if transform != nil {
+			text = transform(text)
+			nt.textLens[i] = int64(len(text))
+		}
+
+		w.Reset()
+		w.Write(text)
+		if nt.texts[i], err = w.Data(); err != nil {
+			return nil, err
+		}
+	}
+
+	// The pooled writers compress at the level of t.
+	if t.smallest != nil {
+		nt.smallest = newSmallestPool(level)
+	}
+
+	if len(nt.tags) == 0 {
+		if nt.template, err = gzipSegment(level, nt.texts[0]); err != nil {
+			return nil, err
+		}
+	}
+
+	// Transformed segments may no longer match the source or fixed
+	// widths.
+	if transform != nil {
+		nt.source, nt.textPos = "", nil
+	}
+
+	if t.patch != nil && transform == nil {
+		if nt.patch, err = newPatchImage(nt, t.patch.tagWidths(t)); err != nil {
+			return nil, err
+		}
+	}
+
+	return nt, nil
+}
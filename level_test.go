@@ -0,0 +1,48 @@
+package gziptemplate
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTemplateWithLevel(t *testing.T) {
+	source := strings.Repeat("<p>lorem ipsum dolor sit amet</p>", 32)
+	tpl := New(source+"[foo]"+source, "[", "]", NoCompression)
+
+	fast, err := tpl.WithLevel(BestCompression)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	m := map[string]interface{}{"foo": "bar"}
+	a, b := tpl.ExecuteBytes(m), fast.ExecuteBytes(m)
+	if len(b) >= len(a) {
+		t.Errorf("expected output at BestCompression (%d bytes) to be smaller than at NoCompression (%d bytes)", len(b), len(a))
+	}
+
+	result := source + "bar" + source
+	if s := decompressBytes(t, b); string(s) != result {
+		t.Fatalf("unexpected template value %q. Expected %q", s, result)
+	}
+
+	static, err := New(source, "[", "]", NoCompression).WithLevel(BestSpeed)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if s := decompressBytes(t, static.ExecuteBytes(nil)); string(s) != source {
+		t.Fatalf("unexpected template value %q. Expected %q", s, source)
+	}
+
+	if _, err := tpl.WithLevel(42); err == nil {
+		t.Fatalf("expected error for invalid level")
+	}
+}
+
+func TestWithLevelKeepsSettings(t *testing.T) {
+	nt, err := settingsTemplate(t).WithLevel(BestSpeed)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	checkSettings(t, nt, "<p>a; b</p>Untitled")
+}
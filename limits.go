@@ -0,0 +1,167 @@
+package gziptemplate
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// Limits bounds the resources a template may use while being parsed and
+// executed. A zero field means no limit. See WithLimits.
+type Limits struct {
+	// MaxSize is the maximum size of the template source in bytes.
+	MaxSize int64
+
+	// MaxTags is the maximum number of tags, including directives and
+	// comments, in the template source.
+	MaxTags int
+
+	// MaxTagLength is the maximum length in bytes of the contents of a
+	// tag, excluding the delimiters. Comments are exempt.
+	MaxTagLength int
+
+	// MaxOutput and MaxUncompressedOutput are the maximum sizes in bytes
+	// of the gzipped and uncompressed output of an execution. Executions
+	// abort once a limit is exceeded, though output already written is
+	// not retracted. Methods that produce no gzipped output, such as
+	// ExecutePlain, enforce only MaxUncompressedOutput.
+	MaxOutput             int64
+	MaxUncompressedOutput int64
+}
+
+// LimitError is returned when a template exceeds one of its Limits. It may
+// be wrapped in a *ParseError and can be detected with errors.As.
+type LimitError struct {
+	// Limit names the field of Limits that was exceeded.
+	Limit string
+
+	// Max is the value of the limit.
+	Max int64
+}
+
+func (e *LimitError) Error() string {
+	return fmt.Sprintf("gziptemplate: template exceeds %s of %d", e.Limit, e.Max)
+}
+
+// outputLimit counts the output of an execution against the MaxOutput and
+// MaxUncompressedOutput fields of limits.
+type outputLimit struct {
+	limits Limits
+
+	compressed, uncompressed int64
+}
+
+// newOutputLimit returns an outputLimit for limits, or nil if they don't
+// bound the output.
+func newOutputLimit(limits Limits) *outputLimit {
+	if limits.MaxOutput <= 0 && limits.MaxUncompressedOutput <= 0 {
+		return nil
+	}
+
+	return &outputLimit{limits: limits}
+}
+
+// checkOutput returns a *LimitError if the complete gzipped output b exceeds
+// the output limits of t.
+func (t *Template) checkOutput(b []byte) error {
+	if ol := newOutputLimit(t.limits); ol != nil {
+		return ol.check(b)
+	}
+
+	return nil
+}
+
+// limitOutput wraps w, to which the gzipped output of an execution is
+// written, to count it against the output limits of t. The returned
+// outputLimit, if non-nil, is to be given to the stream with withLimit.
+func (t *Template) limitOutput(w io.Writer) (io.Writer, *outputLimit) {
+	ol := newOutputLimit(t.limits)
+	if ol == nil {
+		return w, nil
+	}
+
+	return &compressedLimitWriter{w: w, ol: ol}, ol
+}
+
+// withLimit returns s with its output counted against ol, if non-nil.
+func (s stream) withLimit(ol *outputLimit) stream {
+	if ol != nil {
+		s.w, s.limit = &uncompressedLimitWriter{w: s.w, ol: ol}, ol
+	}
+
+	return s
+}
+
+// err returns a *LimitError if either limit has been exceeded.
+func (ol *outputLimit) err() error {
+	if max := ol.limits.MaxUncompressedOutput; max > 0 && ol.uncompressed > max {
+		return &LimitError{"MaxUncompressedOutput", max}
+	}
+	if max := ol.limits.MaxOutput; max > 0 && ol.compressed > max {
+		return &LimitError{"MaxOutput", max}
+	}
+
+	return nil
+}
+
+// check counts the complete gzipped output b.
+func (ol *outputLimit) check(b []byte) error {
+	ol.compressed += int64(len(b))
+	if len(b) >= 4 {
+		ol.uncompressed += int64(binary.LittleEndian.Uint32(b[len(b)-4:]))
+	}
+
+	return ol.err()
+}
+
+// uncompressedLimitWriter counts tag values against ol before writing them
+// to w.
+type uncompressedLimitWriter struct {
+	w  io.Writer
+	ol *outputLimit
+}
+
+func (lw *uncompressedLimitWriter) Write(p []byte) (int, error) {
+	lw.ol.uncompressed += int64(len(p))
+	if err := lw.ol.err(); err != nil {
+		return 0, err
+	}
+
+	return lw.w.Write(p)
+}
+
+// compressedLimitWriter counts the gzipped output written to w against ol.
+type compressedLimitWriter struct {
+	w  io.Writer
+	ol *outputLimit
+}
+
+func (lw *compressedLimitWriter) Write(p []byte) (int, error) {
+	if err := lw.ol.err(); err != nil {
+		return 0, err
+	}
+
+	n, err := lw.w.Write(p)
+	lw.ol.compressed += int64(n)
+	if err == nil {
+		err = lw.ol.err()
+	}
+	return n, err
+}
+
+// sizeLimitReader reads from r, failing once more than max bytes are read.
+type sizeLimitReader struct {
+	r   io.Reader
+	n   int64
+	max int64
+}
+
+func (lr *sizeLimitReader) Read(p []byte) (int, error) {
+	n, err := lr.r.Read(p)
+	lr.n += int64(n)
+	if lr.n > lr.max {
+		return n, &LimitError{"MaxSize", lr.max}
+	}
+
+	return n, err
+}
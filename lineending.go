@@ -0,0 +1,76 @@
+package gziptemplate
+
+// LineEnding is a line ending style used by WithLineEndings.
+type LineEnding int
+
+// These are the supported line ending styles.
+const (
+	// KeepLineEndings leaves line endings untouched.
+	KeepLineEndings LineEnding = iota
+
+	// LF normalizes line endings to "\n".
+	LF
+
+	// CRLF normalizes line endings to "\r\n".
+	CRLF
+)
+
+// lineEndingFilter normalizes line endings in a stream of text.
+type lineEndingFilter struct {
+	mode LineEnding
+
+	// pendingCR is set when the last chunk ended with '\r', which may yet
+	// be followed by '\n'.
+	pendingCR bool
+
+	buf []byte
+}
+
+// write passes p to emit with line endings normalized. The chunk passed to
+// emit is only valid for the duration of the call.
+func (f *lineEndingFilter) write(p []byte, emit func([]byte)) {
+	if f.mode == KeepLineEndings {
+		emit(p)
+		return
+	}
+
+	buf := f.buf[:0]
+	for _, c := range p {
+		if f.pendingCR {
+			f.pendingCR = false
+			buf = f.newline(buf)
+
+			if c == '\n' {
+				continue
+			}
+		}
+
+		switch c {
+		case '\r':
+			f.pendingCR = true
+		case '\n':
+			buf = f.newline(buf)
+		default:
+			buf = append(buf, c)
+		}
+	}
+
+	f.buf = buf
+	emit(buf)
+}
+
+// flush emits any line ending held back by write. It must be called at the
+// end of each run of text.
+func (f *lineEndingFilter) flush(emit func([]byte)) {
+	if f.pendingCR {
+		f.pendingCR = false
+		emit(f.newline(f.buf[:0]))
+	}
+}
+
+func (f *lineEndingFilter) newline(buf []byte) []byte {
+	if f.mode == CRLF {
+		buf = append(buf, '\r')
+	}
+	return append(buf, '\n')
+}
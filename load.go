@@ -0,0 +1,155 @@
+package gziptemplate
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+)
+
+// ParseFiles returns a TemplateSet holding the templates parsed from the
+// named files with the default options of NewTemplateSet.
+//
+// See TemplateSet.ParseFiles for details.
+func ParseFiles(filenames ...string) (*TemplateSet, error) {
+	s := NewTemplateSet()
+	if err := s.ParseFiles(filenames...); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// ParseGlob returns a TemplateSet holding the templates parsed from the
+// files matched by pattern with the default options of NewTemplateSet.
+//
+// See TemplateSet.ParseGlob for details.
+func ParseGlob(pattern string) (*TemplateSet, error) {
+	s := NewTemplateSet()
+	if err := s.ParseGlob(pattern); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// ParseFS returns a TemplateSet holding the templates parsed from the files
+// of fsys matched by patterns with the default options of NewTemplateSet.
+//
+// See TemplateSet.ParseFS for details.
+func ParseFS(fsys fs.FS, patterns ...string) (*TemplateSet, error) {
+	s := NewTemplateSet()
+	if err := s.ParseFS(fsys, patterns...); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// ParseFiles parses the named files and adds them to s, each named by the
+// base name of its file. Files are read and compressed incrementally.
+//
+// The files may include each other and the templates already in s. They
+// may be given in any order, as files whose includes can't yet be satisfied
+// are retried once the others have been parsed.
+func (s *TemplateSet) ParseFiles(filenames ...string) error {
+	if len(filenames) == 0 {
+		return fmt.Errorf("gziptemplate: no files named in call to ParseFiles")
+	}
+
+	return s.parseAll(filenames, filepath.Base, func(name string) (io.ReadCloser, error) {
+		return os.Open(name)
+	})
+}
+
+// ParseGlob parses the files matched by pattern, as with filepath.Glob, and
+// adds them to s as with ParseFiles.
+func (s *TemplateSet) ParseGlob(pattern string) error {
+	filenames, err := filepath.Glob(pattern)
+	if err != nil {
+		return err
+	}
+	if len(filenames) == 0 {
+		return fmt.Errorf("gziptemplate: pattern matches no files: %#q", pattern)
+	}
+
+	return s.ParseFiles(filenames...)
+}
+
+// ParseFS parses the files of fsys matched by patterns, as with fs.Glob, and
+// adds them to s as with ParseFiles. This allows templates to be loaded from
+// an embed.FS.
+func (s *TemplateSet) ParseFS(fsys fs.FS, patterns ...string) error {
+	var filenames []string
+	for _, pattern := range patterns {
+		matches, err := fs.Glob(fsys, pattern)
+		if err != nil {
+			return err
+		}
+		if len(matches) == 0 {
+			return fmt.Errorf("gziptemplate: pattern matches no files: %#q", pattern)
+		}
+
+		filenames = append(filenames, matches...)
+	}
+	if len(filenames) == 0 {
+		return fmt.Errorf("gziptemplate: no files named in call to ParseFS")
+	}
+
+	return s.parseAll(filenames, path.Base, func(name string) (io.ReadCloser, error) {
+		return fsys.Open(name)
+	})
+}
+
+// parseAll parses the named files and adds them to s. Parsing is repeated
+// until every file parses or no further progress is made, so that files
+// may include those that follow them.
+func (s *TemplateSet) parseAll(filenames []string, base func(string) string, open func(string) (io.ReadCloser, error)) error {
+	for len(filenames) != 0 {
+		var (
+			pending  []string
+			firstErr error
+		)
+		for _, filename := range filenames {
+			err := s.parseFile(base(filename), filename, open)
+			switch {
+			case err == nil:
+			case os.IsNotExist(err):
+				return err
+			default:
+				pending = append(pending, filename)
+				if firstErr == nil {
+					firstErr = fmt.Errorf("gziptemplate: %s: %w", filename, err)
+				}
+			}
+		}
+
+		if len(pending) == len(filenames) {
+			return firstErr
+		}
+
+		filenames = pending
+	}
+
+	return nil
+}
+
+// parseFile parses the named file and adds it to s under name.
+func (s *TemplateSet) parseFile(name, filename string, open func(string) (io.ReadCloser, error)) error {
+	f, err := open(filename)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	opts := append(s.opts[:len(s.opts):len(s.opts)], WithTemplateSet(s))
+	o := newOptions(opts)
+
+	t, err := NewTemplateFromReader(f, o.startTag, o.endTag, o.level, opts...)
+	if err != nil {
+		return err
+	}
+
+	t.name = name
+	s.Add(name, t)
+	return nil
+}
@@ -0,0 +1,108 @@
+package gziptemplate
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+)
+
+var loadFiles = map[string]string{
+	"page.html":   `{{#include "header.html"}}<p>{{body}}</p>{{#include "footer.html"}}`,
+	"header.html": `<h1>{{title}}</h1>`,
+	"footer.html": `<footer/>`,
+}
+
+func checkLoadedSet(t *testing.T, s *TemplateSet) {
+	t.Helper()
+
+	for name := range loadFiles {
+		if s.Lookup(name) == nil {
+			t.Fatalf("missing template %q", name)
+		}
+	}
+
+	b := s.Lookup("page.html").ExecuteBytes(map[string]interface{}{
+		"title": "Title",
+		"body":  "Body",
+	})
+	result := "<h1>Title</h1><p>Body</p><footer/>"
+	if s := decompressBytes(t, b); string(s) != result {
+		t.Fatalf("unexpected template value %q. Expected %q", s, result)
+	}
+}
+
+func TestParseFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gziptemplate")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	var filenames []string
+	for name, text := range loadFiles {
+		filename := filepath.Join(dir, name)
+		if err := ioutil.WriteFile(filename, []byte(text), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		filenames = append(filenames, filename)
+	}
+
+	s, err := ParseFiles(filenames...)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	checkLoadedSet(t, s)
+
+	s, err = ParseGlob(filepath.Join(dir, "*.html"))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	checkLoadedSet(t, s)
+
+	if _, err := ParseGlob(filepath.Join(dir, "*.txt")); err == nil {
+		t.Fatal("expected error for pattern matching no files")
+	}
+	if _, err := ParseFiles(filepath.Join(dir, "missing.html")); err == nil {
+		t.Fatal("expected error for missing file")
+	}
+}
+
+func TestParseFS(t *testing.T) {
+	fsys := make(fstest.MapFS)
+	for name, text := range loadFiles {
+		fsys["templates/"+name] = &fstest.MapFile{Data: []byte(text)}
+	}
+
+	s, err := ParseFS(fsys, "templates/*.html")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	checkLoadedSet(t, s)
+
+	fsys["templates/broken.html"] = &fstest.MapFile{Data: []byte(`{{#include "missing"}}`)}
+	if _, err := ParseFS(fsys, "templates/*.html"); err == nil {
+		t.Fatal("expected error for unsatisfiable include")
+	}
+}
+
+func TestTemplateSetOptions(t *testing.T) {
+	fsys := fstest.MapFS{
+		"a": &fstest.MapFile{Data: []byte("[foo]")},
+	}
+
+	s := NewTemplateSet(WithDelims("[", "]"), WithLevel(BestSpeed))
+	if err := s.ParseFS(fsys, "a"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	tpl := s.Lookup("a")
+	if tpl.level != BestSpeed {
+		t.Fatalf("unexpected level %d. Expected %d", tpl.level, BestSpeed)
+	}
+	if s := decompressBytes(t, tpl.ExecuteBytes(map[string]interface{}{"foo": "111"})); string(s) != "111" {
+		t.Fatalf("unexpected template value %q. Expected %q", s, "111")
+	}
+}
@@ -0,0 +1,72 @@
+package gziptemplate
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"sync"
+)
+
+// LogWriter appends each execution of a template to an underlying writer as
+// an independent gzip member.
+//
+// As a gzip file may consist of any number of concatenated members, the
+// output of a LogWriter is itself a valid gzip file that zcat and
+// compress/gzip will read as the concatenation of every rendered event.
+//
+// A LogWriter is safe for concurrent use by multiple goroutines.
+type LogWriter struct {
+	mu  sync.Mutex
+	w   io.Writer
+	buf bytes.Buffer
+}
+
+// NewLogWriter returns a LogWriter that appends to w.
+func NewLogWriter(w io.Writer) *LogWriter {
+	return &LogWriter{w: w}
+}
+
+// OpenLogFile opens the named file for appending, creating it if it does not
+// exist, and returns a LogWriter that writes to it.
+func OpenLogFile(name string) (*LogWriter, error) {
+	f, err := os.OpenFile(name, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+	return NewLogWriter(f), nil
+}
+
+// ExecuteFunc appends the result of t.ExecuteFunc as a new gzip member.
+//
+// The member is rendered in full before being written with a single call to
+// Write so that a failing TagFunc never leaves a partial member behind.
+func (l *LogWriter) ExecuteFunc(t *Template, f TagFunc) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.buf.Reset()
+	if err := t.ExecuteFunc(&l.buf, f); err != nil {
+		return err
+	}
+
+	_, err := l.w.Write(l.buf.Bytes())
+	return err
+}
+
+// Execute appends the result of t.Execute as a new gzip member.
+//
+// See ExecuteFunc for details.
+func (l *LogWriter) Execute(t *Template, m map[string]interface{}) error {
+	return l.ExecuteFunc(t, t.mapTagFunc(m))
+}
+
+// Close closes the underlying writer if it implements io.Closer.
+func (l *LogWriter) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if c, ok := l.w.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
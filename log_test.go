@@ -0,0 +1,83 @@
+package gziptemplate
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLogWriter(t *testing.T) {
+	tpl := New("event=[name]\n", "[", "]", BestCompression)
+
+	var buf bytes.Buffer
+	l := NewLogWriter(&buf)
+
+	for _, name := range []string{"foo", "bar", "baz"} {
+		if err := l.Execute(tpl, map[string]interface{}{"name": name}); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	}
+
+	s := decompressBytes(t, buf.Bytes())
+	result := "event=foo\nevent=bar\nevent=baz\n"
+	if string(s) != result {
+		t.Fatalf("unexpected log contents %q. Expected %q", s, result)
+	}
+}
+
+func TestLogWriterError(t *testing.T) {
+	tpl := New("event=[name]\n", "[", "]", BestCompression)
+
+	var buf bytes.Buffer
+	l := NewLogWriter(&buf)
+
+	errTag := errors.New("tag error")
+	if err := l.ExecuteFunc(tpl, func(w io.Writer, tag string) error {
+		return errTag
+	}); !errors.Is(err, errTag) {
+		t.Fatalf("unexpected error %v. Expected %v", err, errTag)
+	}
+
+	if buf.Len() != 0 {
+		t.Fatalf("unexpected partial member of %d bytes written", buf.Len())
+	}
+}
+
+func TestOpenLogFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gziptemplate")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	name := filepath.Join(dir, "events.log.gz")
+	tpl := New("[name];", "[", "]", BestCompression)
+
+	for _, event := range []string{"foo", "bar"} {
+		l, err := OpenLogFile(name)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if err := l.Execute(tpl, map[string]interface{}{"name": event}); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if err := l.Close(); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	}
+
+	b, err := ioutil.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := decompressBytes(t, b)
+	result := "foo;bar;"
+	if string(s) != result {
+		t.Fatalf("unexpected log contents %q. Expected %q", s, result)
+	}
+}
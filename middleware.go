@@ -0,0 +1,36 @@
+package gziptemplate
+
+import (
+	"fmt"
+	"io"
+)
+
+// Middleware wraps a TagFunc to add behaviour, such as logging, timing,
+// escaping or panic recovery, to every tag resolution.
+//
+// Middleware is composed like net/http middleware: it receives the next
+// TagFunc in the chain and returns a TagFunc that is expected to call it.
+type Middleware func(next TagFunc) TagFunc
+
+// Chain returns f wrapped by each of mw. The first middleware is the
+// outermost and so is the first to see each tag.
+func Chain(f TagFunc, mw ...Middleware) TagFunc {
+	for i := len(mw) - 1; i >= 0; i-- {
+		f = mw[i](f)
+	}
+	return f
+}
+
+// Recover is a Middleware that recovers from panics in the wrapped TagFunc
+// and returns them as errors.
+func Recover(next TagFunc) TagFunc {
+	return func(w io.Writer, tag string) (err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				err = fmt.Errorf("gziptemplate: panic in TagFunc for tag=%q: %v", tag, r)
+			}
+		}()
+
+		return next(w, tag)
+	}
+}
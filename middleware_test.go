@@ -0,0 +1,92 @@
+package gziptemplate
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+)
+
+func upperMiddleware(next TagFunc) TagFunc {
+	return func(w io.Writer, tag string) error {
+		var buf bytes.Buffer
+		if err := next(&buf, tag); err != nil {
+			return err
+		}
+
+		_, err := io.WriteString(w, strings.ToUpper(buf.String()))
+		return err
+	}
+}
+
+func traceMiddleware(trace *[]string, name string) Middleware {
+	return func(next TagFunc) TagFunc {
+		return func(w io.Writer, tag string) error {
+			*trace = append(*trace, name+":"+tag)
+			return next(w, tag)
+		}
+	}
+}
+
+func TestChain(t *testing.T) {
+	var trace []string
+	tpl := New("foo[foo]bar", "[", "]", BestCompression)
+
+	s := tpl.ExecuteFuncBytes(Chain(func(w io.Writer, tag string) error {
+		_, err := io.WriteString(w, "abc")
+		return err
+	}, traceMiddleware(&trace, "a"), upperMiddleware, traceMiddleware(&trace, "b")))
+	s = decompressBytes(t, s)
+
+	result := "fooABCbar"
+	if string(s) != result {
+		t.Fatalf("unexpected template value %q. Expected %q", s, result)
+	}
+
+	if strings.Join(trace, ",") != "a:foo,b:foo" {
+		t.Fatalf("unexpected middleware order %q", trace)
+	}
+}
+
+func TestWithMiddleware(t *testing.T) {
+	tpl := New("foo[foo]bar", "[", "]", BestCompression, WithMiddleware(upperMiddleware))
+
+	s := decompressBytes(t, tpl.ExecuteBytes(map[string]interface{}{"foo": "abc"}))
+	result := "fooABCbar"
+	if string(s) != result {
+		t.Fatalf("unexpected template value %q. Expected %q", s, result)
+	}
+}
+
+func TestRecover(t *testing.T) {
+	tpl := New("foo[foo]bar", "[", "]", BestCompression, WithMiddleware(Recover))
+
+	var buf bytes.Buffer
+	if err := tpl.ExecuteFunc(&buf, func(w io.Writer, tag string) error {
+		panic("boom")
+	}); err == nil {
+		t.Fatalf("expected non-nil error. got nil")
+	}
+}
+
+func TestWithMiddlewareConditions(t *testing.T) {
+	tpl := New("[#if a]A[#else]!A[#end] [#if b]B[#else]!B[#end] [title|untitled]", "[", "]", BestCompression, WithMiddleware(upperMiddleware))
+
+	s := decompressBytes(t, tpl.ExecuteBytes(map[string]interface{}{"a": false, "b": true}))
+	result := "!A B untitled"
+	if string(s) != result {
+		t.Fatalf("unexpected template value %q. Expected %q", s, result)
+	}
+}
+
+func TestWithMiddlewareRange(t *testing.T) {
+	tpl := New("[#range rows]<[name]>[#end]", "[", "]", BestCompression, WithMiddleware(upperMiddleware))
+
+	s := decompressBytes(t, tpl.ExecuteBytes(map[string]interface{}{
+		"rows": []map[string]interface{}{{"name": "a"}, {"name": "b"}},
+	}))
+	result := "<A><B>"
+	if string(s) != result {
+		t.Fatalf("unexpected template value %q. Expected %q", s, result)
+	}
+}
@@ -0,0 +1,112 @@
+package gziptemplate
+
+// rawElements are the HTML elements whose content is not minified.
+var rawElements = []string{"pre", "script", "style", "textarea"}
+
+// htmlMinifier collapses runs of whitespace in a stream of HTML text.
+type htmlMinifier struct {
+	// space is the whitespace character held back from the current run,
+	// '\n' if the run contains a newline, or zero if there is none.
+	space byte
+
+	// name accumulates the name of the element being opened, and open
+	// reports whether one is being read.
+	name []byte
+	open bool
+
+	// raw is the end tag, such as "</pre", that ends the element whose
+	// content is being passed through untouched, and match is how much of
+	// it has been seen.
+	raw   string
+	match int
+
+	buf []byte
+}
+
+// write passes p to emit with whitespace collapsed. The chunk passed to emit
+// is only valid for the duration of the call.
+func (m *htmlMinifier) write(p []byte, emit func([]byte)) {
+	buf := m.buf[:0]
+	for _, c := range p {
+		if m.raw != "" {
+			buf = append(buf, c)
+			m.rawByte(c)
+			continue
+		}
+
+		if isSpace(c) {
+			if m.space != '\n' {
+				m.space = ' '
+				if c == '\n' {
+					m.space = '\n'
+				}
+			}
+
+			m.element(c)
+			continue
+		}
+
+		if m.space != 0 {
+			buf = append(buf, m.space)
+			m.space = 0
+		}
+
+		buf = append(buf, c)
+		m.element(c)
+	}
+
+	m.buf = buf
+	emit(buf)
+}
+
+// flush emits any whitespace held back by write. It must be called at the
+// end of each run of text.
+func (m *htmlMinifier) flush(emit func([]byte)) {
+	if m.space != 0 {
+		emit([]byte{m.space})
+		m.space = 0
+	}
+}
+
+// element tracks the start tags of raw elements.
+func (m *htmlMinifier) element(c byte) {
+	switch {
+	case c == '<':
+		m.open, m.name = true, m.name[:0]
+	case !m.open:
+	case 'a' <= lower(c) && lower(c) <= 'z':
+		if len(m.name) < len("textarea") {
+			m.name = append(m.name, lower(c))
+		} else {
+			m.open = false
+		}
+	default:
+		m.open = false
+		for _, name := range rawElements {
+			if string(m.name) == name {
+				m.raw, m.match = "</"+name, 0
+			}
+		}
+	}
+}
+
+// rawByte looks for the end tag of the raw element in its content.
+func (m *htmlMinifier) rawByte(c byte) {
+	if lower(c) == m.raw[m.match] {
+		m.match++
+	} else if m.match = 0; c == '<' {
+		m.match = 1
+	}
+
+	if m.match == len(m.raw) {
+		m.raw = ""
+	}
+}
+
+// lower returns the lower case of the ASCII letter c, or c itself.
+func lower(c byte) byte {
+	if 'A' <= c && c <= 'Z' {
+		return c + 'a' - 'A'
+	}
+	return c
+}
@@ -0,0 +1,53 @@
+package gziptemplate
+
+import (
+	"io"
+
+	"go.tmthrgd.dev/gzipbuilder"
+)
+
+// Bind returns a substitution value that renders t with values from m.
+//
+// A *Template given directly as a value shares the values of the template it
+// is nested in. Either way, where possible the precompressed static segments
+// of t are spliced into the output rather than being decompressed and
+// compressed again, which makes composing fragments at execution time cheap.
+func (t *Template) Bind(m map[string]interface{}) TagFunc {
+	return func(w io.Writer, tag string) error {
+		return t.writeTo(w, t.mapTagFunc(m))
+	}
+}
+
+// writeTo renders t to w, the writer passed to a TagFunc, calling f on each
+// tag occurrence.
+func (t *Template) writeTo(w io.Writer, f TagFunc) error {
+	if sw, ok := w.(*spliceWriter); ok {
+		t.stats.executed()
+		return t.execute(sw.s, f)
+	}
+
+	// Values that are escaped or otherwise transformed must see the
+	// uncompressed text.
+	t.stats.executed()
+	return t.render(w, f)
+}
+
+// spliceWriter is passed to TagFuncs during execution. It allows nested
+// templates to splice their precompressed segments into s.
+type spliceWriter struct {
+	s stream
+}
+
+func (sw *spliceWriter) Write(p []byte) (int, error) {
+	return sw.s.w.Write(p)
+}
+
+// render writes the uncompressed output of t to w, calling f on each tag
+// occurrence.
+func (t *Template) render(w io.Writer, f TagFunc) error {
+	return t.execute(stream{
+		add:   func(*gzipbuilder.PrecompressedData) {},
+		w:     w,
+		plain: w,
+	}, f)
+}
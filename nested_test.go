@@ -0,0 +1,36 @@
+package gziptemplate
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestNestedTemplate(t *testing.T) {
+	item := New("<li>[name]</li>", "[", "]", BestCompression)
+	page := New("<ul>[first][second]</ul><p>[name]</p>[escaped]", "[", "]", BestCompression,
+		WithEscaping(map[string]Escaping{
+			"escaped": EscapeHTML,
+		}))
+
+	m := map[string]interface{}{
+		"name":    "shared",
+		"first":   item,
+		"second":  item.Bind(map[string]interface{}{"name": "bound"}),
+		"escaped": item,
+	}
+
+	result := "<ul><li>shared</li><li>bound</li></ul><p>shared</p>&lt;li&gt;shared&lt;/li&gt;"
+
+	s := decompressBytes(t, page.ExecuteBytes(m))
+	if string(s) != result {
+		t.Fatalf("unexpected template value %q. Expected %q", s, result)
+	}
+
+	var buf bytes.Buffer
+	if _, err := page.ExecuteMapped(&buf, m); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if s := decompressBytes(t, buf.Bytes()); string(s) != result {
+		t.Fatalf("unexpected template value %q. Expected %q", s, result)
+	}
+}
@@ -0,0 +1,70 @@
+package gziptemplate
+
+import (
+	"io"
+	"time"
+)
+
+// Observer is notified of each substitution made while a template is
+// executed. See WithObserver.
+type Observer interface {
+	// Observe is called after the value for tag has been written, with
+	// the number of uncompressed bytes written and the time taken,
+	// including any compression done meanwhile. err is the error
+	// returned for the value, if any.
+	//
+	// Observe may be called concurrently by executions in different
+	// goroutines.
+	Observe(tag string, n int64, d time.Duration, err error)
+}
+
+// ObserverFunc adapts an ordinary function to the Observer interface.
+type ObserverFunc func(tag string, n int64, d time.Duration, err error)
+
+// Observe calls f(tag, n, d, err).
+func (f ObserverFunc) Observe(tag string, n int64, d time.Duration, err error) {
+	f(tag, n, d, err)
+}
+
+// observation counts the output of a tag value for an Observer. Static
+// segments spliced in by nested templates are counted by stream.text, which
+// adds to each enclosing observation in turn.
+type observation struct {
+	n      int64
+	parent *observation
+}
+
+// observe returns s and tw, the writer for tag values, modified to count the
+// output of a value in a new observation.
+func (s stream) observe(tw io.Writer) (stream, io.Writer, *observation) {
+	ob := &observation{parent: s.obs}
+
+	s.obs = ob
+	s.w = &observedWriter{w: s.w, ob: ob}
+
+	if _, ok := tw.(*spliceWriter); ok {
+		return s, &spliceWriter{s}, ob
+	}
+
+	return s, s.w, ob
+}
+
+// observed counts n bytes added to s other than through w in each enclosing
+// observation.
+func (s stream) observed(n int64) {
+	for ob := s.obs; ob != nil; ob = ob.parent {
+		ob.n += n
+	}
+}
+
+// observedWriter counts writes to w in ob.
+type observedWriter struct {
+	w  io.Writer
+	ob *observation
+}
+
+func (ow *observedWriter) Write(p []byte) (int, error) {
+	n, err := ow.w.Write(p)
+	ow.ob.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,65 @@
+package gziptemplate
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+type observed struct {
+	tag string
+	n   int64
+	err error
+}
+
+func TestWithObserver(t *testing.T) {
+	var got []observed
+	obs := ObserverFunc(func(tag string, n int64, d time.Duration, err error) {
+		if d < 0 {
+			t.Errorf("negative duration %s for tag=%q", d, tag)
+		}
+		got = append(got, observed{tag, n, err})
+	})
+
+	errTag := errors.New("tag error")
+
+	for _, opts := range [][]Option{
+		{WithObserver(obs)},
+		{WithObserver(obs), WithSmallestValues()},
+	} {
+		got = got[:0]
+
+		tpl := New("a[foo]b[#if cond][bar][#end][nested][fail]", "[", "]", BestCompression, opts...)
+		_, err := tpl.ExecuteBytesErr(map[string]interface{}{
+			"foo":  strings.Repeat("x", 100),
+			"cond": true,
+			"bar": TagWriterFunc(func(w io.Writer, tag string) (int, error) {
+				return io.WriteString(w, "yz")
+			}),
+			"nested": New("<[foo]>", "[", "]", BestSpeed),
+			"fail": TagFunc(func(w io.Writer, tag string) error {
+				return errTag
+			}),
+		})
+		if !errors.Is(err, errTag) {
+			t.Fatalf("unexpected error %v. Expected %v", err, errTag)
+		}
+
+		expect := []observed{
+			{"foo", 100, nil},
+			{"bar", 2, nil},
+			{"nested", 102, nil},
+			{"fail", 0, errTag},
+		}
+		if len(got) != len(expect) {
+			t.Fatalf("unexpected observations %v. Expected %v", got, expect)
+		}
+		for i := range expect {
+			if got[i] != expect[i] {
+				t.Fatalf("unexpected observation %v. Expected %v", got[i], expect[i])
+			}
+		}
+	}
+}
@@ -0,0 +1,360 @@
+package gziptemplate
+
+import (
+	"fmt"
+	"io"
+)
+
+// Option configures how a template is compiled.
+type Option func(*options)
+
+type options struct {
+	flags map[string]bool
+
+	escaping map[string]Escaping
+
+	lineEnding LineEnding
+
+	middleware []Middleware
+
+	widths map[string]int
+
+	smallest bool
+
+	set *TemplateSet
+
+	filters map[string]Filter
+
+	missing TagFunc
+
+	limits Limits
+
+	foldCase bool
+
+	minify bool
+
+	constants map[string]interface{}
+
+	keepSource bool
+	dropSource bool
+
+	hook ParseHook
+
+	panics bool
+
+	observer Observer
+
+	autoEscape bool
+
+	separator *string
+
+	// These are only used by NewWithOptions.
+	startTag, endTag string
+	level            int
+}
+
+// newOptions applies opts to the defaults used by NewWithOptions.
+func newOptions(opts []Option) options {
+	o := options{
+		startTag: "{{",
+		endTag:   "}}",
+		level:    DefaultCompression,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// WithFlags resolves conditional sections against flags at compile time.
+//
+// A conditional section is written, with "[" and "]" as delimiters, as:
+//
+//	[#if name]...[#else]...[#end]
+//
+// The [#else] branch is optional and sections may be nested. The branch not
+// selected by flags[name] is dropped entirely: its text is never compressed
+// and its tags never substituted. Sections referring to a name not present
+// in flags are instead resolved at execution time, see NewTemplate.
+//
+// This allows lean templates to be produced for each build configuration
+// from a single source.
+func WithFlags(flags map[string]bool) Option {
+	return func(o *options) {
+		o.flags = flags
+	}
+}
+
+// WithMiddleware wraps every tag resolution of the template with mw, in
+// addition to any middleware passed to Chain at execution time. The
+// conditions of conditional and repeated sections, and of defaults, are
+// resolved without it.
+//
+// See Chain for the order in which middleware is applied.
+func WithMiddleware(mw ...Middleware) Option {
+	return func(o *options) {
+		o.middleware = append(o.middleware, mw...)
+	}
+}
+
+// WithEscaping declares an escaping policy for tags by name. Values for
+// those tags are escaped at execution time however they are supplied, be it
+// in a map, by a Provider or by a TagFunc.
+//
+// This is a lighter-weight alternative to WithAutoEscape where the context
+// of each tag is known in advance.
+func WithEscaping(policy map[string]Escaping) Option {
+	return func(o *options) {
+		o.escaping = policy
+	}
+}
+
+// WithLineEndings normalizes all line endings in the static text of the
+// template to le before it is compressed. CRLF, lone CR and LF are all
+// recognised as line endings. Tag values are not affected.
+//
+// This avoids mixed line endings, and the poorer compression that comes
+// with them, in templates edited on different platforms.
+func WithLineEndings(le LineEnding) Option {
+	return func(o *options) {
+		o.lineEnding = le
+	}
+}
+
+// WithFixedWidths declares that the values of tags are always exactly as many
+// bytes wide as given in widths, after any escaping.
+//
+// If every tag of the template has a fixed width, its values are emitted in
+// stored deflate blocks. ExecuteFunc and ExecuteFuncBytes then patch values
+// directly into a copy of the precompressed output and fix up the CRC-32,
+// which is much faster than compressing them. A value of the wrong width is
+// an error. Widths must be between 1 and 65535.
+//
+// This suits fixed-format values such as request IDs and timestamps.
+func WithFixedWidths(widths map[string]int) Option {
+	return func(o *options) {
+		o.widths = widths
+	}
+}
+
+// WithSmallestValues has each tag value emitted in whichever of compressed
+// or stored (uncompressed) deflate blocks is smaller.
+//
+// This prevents already-compressed or random values from expanding the
+// output, at the cost of buffering and trial-compressing every value.
+func WithSmallestValues() Option {
+	return func(o *options) {
+		o.smallest = true
+	}
+}
+
+// WithTemplateSet allows the template to include the templates of set with
+// the include directive, written with "[" and "]" as delimiters as:
+//
+//	[#include "name"]
+//
+// The precompressed segments of the named template are embedded at compile
+// time, so including is no more expensive than the template it includes.
+// The included template keeps its own escaping policies but takes on the
+// middleware of the template that includes it.
+func WithTemplateSet(set *TemplateSet) Option {
+	return func(o *options) {
+		o.set = set
+	}
+}
+
+// WithFilters makes filters available to the template in addition to the
+// built-in filters, replacing any of the same name. See Filter.
+func WithFilters(filters map[string]Filter) Option {
+	return func(o *options) {
+		o.filters = filters
+	}
+}
+
+// WithDelims sets the tag start and tag end delimiters of a template created
+// with NewWithOptions or loaded into a TemplateSet. It is ignored by the
+// other constructors, which take the delimiters as arguments.
+func WithDelims(startTag, endTag string) Option {
+	return func(o *options) {
+		o.startTag, o.endTag = startTag, endTag
+	}
+}
+
+// WithLevel sets the compression level of a template created with
+// NewWithOptions or loaded into a TemplateSet. It is ignored by the other
+// constructors, which take the level as an argument.
+func WithLevel(level int) Option {
+	return func(o *options) {
+		o.level = level
+	}
+}
+
+// WithStrict makes it an error to execute the template without a value, or
+// default, for every tag. Conditional and repeated sections and tags with a
+// default in the template are exempt. The error is a *MissingTagError naming
+// the first tag without a value.
+func WithStrict() Option {
+	return WithMissingHandler(func(w io.Writer, tag string) error {
+		return &MissingTagError{Tag: tag}
+	})
+}
+
+// MissingTagError is returned when executing a template compiled with
+// WithStrict without a value for a tag.
+type MissingTagError struct {
+	Tag string
+}
+
+func (e *MissingTagError) Error() string {
+	return fmt.Sprintf("gziptemplate: missing value for tag=%q", e.Tag)
+}
+
+// WithMissingHandler sets a TagFunc called in place of tags without a value,
+// or default, when executing the template with a map or Provider. It is not
+// called for the conditions of conditional and repeated sections, or for
+// tags with a default in the template.
+func WithMissingHandler(f TagFunc) Option {
+	return func(o *options) {
+		o.missing = f
+	}
+}
+
+// WithLimits bounds the resources a template may use while being parsed,
+// which guards against pathological templates from untrusted sources. A
+// template exceeding a limit is rejected with a *LimitError.
+//
+// The output limits are kept with the template and bound its executions, as
+// a backstop against runaway TagFuncs. Executions exceeding them fail with a
+// *LimitError.
+func WithLimits(l Limits) Option {
+	return func(o *options) {
+		o.limits = l
+	}
+}
+
+// WithCaseInsensitive makes the tags of the template match the keys of the
+// maps passed to Execute, and of the items of repeated sections, without
+// regard to case, so that [Foo] matches the key "foo". An exact match is
+// preferred. Providers and the members named by dotted tags are unaffected.
+func WithCaseInsensitive() Option {
+	return func(o *options) {
+		o.foldCase = true
+	}
+}
+
+// WithMinifyHTML collapses each run of whitespace in the static text of the
+// template to a single space, or newline if it contains one, before it is
+// compressed. The content of pre, textarea, script and style elements is left
+// untouched. Tag values are not affected.
+//
+// Smaller static text compresses faster and to a smaller size. Tag offsets,
+// as reported in source maps and errors, still refer to the template source.
+func WithMinifyHTML() Option {
+	return func(o *options) {
+		o.minify = true
+	}
+}
+
+// WithConstants substitutes the tags given a value in constants while the
+// template is parsed, as with Execute, so that they are compressed along with
+// the static text. A constant replaces any default given in the template and
+// values given for the tag at execution time are ignored.
+//
+// This suits values, such as asset version hashes, that are constant for
+// the life of the program. See also Derive.
+func WithConstants(constants map[string]interface{}) Option {
+	return func(o *options) {
+		o.constants = constants
+	}
+}
+
+// WithSource keeps a copy of the template source, to be returned by Source,
+// when parsing from a reader, chunks or bytes. The copy is as large as the
+// template, so parsing no longer takes bounded memory.
+func WithSource() Option {
+	return func(o *options) {
+		o.keepSource = true
+	}
+}
+
+// WithoutSource discards the template source once it is parsed, rather than
+// keeping it to be returned by Source, which saves memory for large
+// templates.
+func WithoutSource() Option {
+	return func(o *options) {
+		o.dropSource = true
+	}
+}
+
+// ParseHook is called with the name of each tag, and of the value of each
+// conditional and repeated section, as the template is parsed. It returns the
+// name to use in its place, or an error to reject the template.
+type ParseHook func(tag string) (string, error)
+
+// WithParseHook calls hook for each tag name as the template is parsed. This
+// allows tag names to be checked against a naming policy, canonicalized or
+// namespaced as the template is compiled.
+//
+// The name is rewritten before escaping policies, constants and flags are
+// applied, so those refer to the name returned by hook.
+func WithParseHook(hook ParseHook) Option {
+	return func(o *options) {
+		o.hook = hook
+	}
+}
+
+// WithPanicOnError makes errors from TagFuncs, middleware and substitution
+// values panic when the template is executed by any of the Execute* methods,
+// as ExecuteBytes and ExecuteFuncBytes always do, rather than being returned.
+// Errors writing the output are still returned.
+//
+// This gives a single error policy to programs that treat such errors as
+// bugs.
+func WithPanicOnError() Option {
+	return func(o *options) {
+		o.panics = true
+	}
+}
+
+// WithObserver sets obs to be notified of each substitution made while the
+// template is executed, with the number of bytes written for it and the
+// time it took. This allows slow or oversized values to be found without
+// instrumenting every TagFunc.
+//
+// The conditions of conditional and repeated sections are not observed.
+func WithObserver(obs Observer) Option {
+	return func(o *options) {
+		o.observer = obs
+	}
+}
+
+// WithAutoEscape makes the parser escape each value according to the HTML
+// context in which its tag appears: as HTML text, within an attribute value,
+// as a URL query component or within a script. Tags with a policy given by
+// WithEscaping keep it.
+//
+// Values within scripts or event handler attributes, but outside of string
+// literals, are written as quoted string literals. Values at the start of a
+// URL attribute with a scheme other than http, https or mailto have the rest
+// of the URL replaced by "#ZgotmplZ", as with html/template. Tags within
+// element names, between attributes, within comments, declarations, style
+// elements or style attributes, within JavaScript comments or within
+// template literals are rejected.
+//
+// The context is inferred from the preceding static text of the template
+// alone, without regard to the branches of sections or to included
+// templates, so it is an aid rather than a guarantee.
+func WithAutoEscape() Option {
+	return func(o *options) {
+		o.autoEscape = true
+	}
+}
+
+// WithSeparator sets the separator written between the elements of []string
+// and [][]byte values, which is otherwise a comma. A Joined value gives its
+// own separator.
+func WithSeparator(sep string) Option {
+	return func(o *options) {
+		o.separator = &sep
+	}
+}
@@ -0,0 +1,476 @@
+package gziptemplate
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestWithFlags(t *testing.T) {
+	template := "a[#if foo]b[bar][#if baz]c[#else]d[#end]e[#else]f[bar][#end]g"
+	m := map[string]interface{}{"bar": "111"}
+
+	for _, test := range []struct {
+		flags  map[string]bool
+		result string
+		tags   int
+	}{
+		{map[string]bool{"foo": true, "baz": true}, "ab111ceg", 1},
+		{map[string]bool{"foo": true, "baz": false}, "ab111deg", 1},
+		{map[string]bool{"foo": false, "baz": true}, "af111g", 1},
+		{map[string]bool{"foo": false, "baz": false}, "af111g", 1},
+	} {
+		tpl := New(template, "[", "]", BestCompression, WithFlags(test.flags))
+		if len(tpl.tags) != test.tags {
+			t.Fatalf("unexpected number of tags %d. Expected %d", len(tpl.tags), test.tags)
+		}
+
+		s := decompressBytes(t, tpl.ExecuteBytes(m))
+		if string(s) != test.result {
+			t.Fatalf("unexpected template value %q for %v. Expected %q", s, test.flags, test.result)
+		}
+	}
+}
+
+func TestWithFlagsDropsTags(t *testing.T) {
+	tpl := New("a[#if foo][bar][#end]b", "[", "]", BestCompression, WithFlags(map[string]bool{"foo": false}))
+	if len(tpl.tags) != 0 {
+		t.Fatalf("unexpected number of tags %d. Expected %d", len(tpl.tags), 0)
+	}
+
+	s := decompressBytes(t, tpl.ExecuteBytes(nil))
+	if string(s) != "ab" {
+		t.Fatalf("unexpected template value %q. Expected %q", s, "ab")
+	}
+}
+
+func TestWithFlagsErrors(t *testing.T) {
+	flags := WithFlags(map[string]bool{"foo": true})
+	for _, template := range []string{
+		"[#if foo]x",
+		"x[#end]",
+		"x[#else]",
+		"[#if foo]x[#else]y[#else]z[#end]",
+		"[#if]x[#end]",
+		"[#if foo bar]x[#end]",
+		"[#unknown]",
+		"[#]",
+	} {
+		if _, err := NewTemplate(template, "[", "]", BestCompression, flags); err == nil {
+			t.Fatalf("expected non-nil error for %q. got nil", template)
+		}
+	}
+}
+
+func TestWithLineEndings(t *testing.T) {
+	template := "a\r\nb\rc\nd\r[foo]\ne\r\r\n\n"
+
+	for _, test := range []struct {
+		le     LineEnding
+		result string
+	}{
+		{KeepLineEndings, "a\r\nb\rc\nd\r1\r\n2\ne\r\r\n\n"},
+		{LF, "a\nb\nc\nd\n1\r\n2\ne\n\n\n"},
+		{CRLF, "a\r\nb\r\nc\r\nd\r\n1\r\n2\r\ne\r\n\r\n\r\n"},
+	} {
+		tpl := New(template, "[", "]", BestCompression, WithLineEndings(test.le))
+
+		s := decompressBytes(t, tpl.ExecuteBytes(map[string]interface{}{"foo": "1\r\n2"}))
+		if string(s) != test.result {
+			t.Fatalf("unexpected template value %q. Expected %q", s, test.result)
+		}
+	}
+}
+
+func TestWithLineEndingsStraddlingBuffer(t *testing.T) {
+	template := strings.Repeat("a", parseBufferSize-1) + "\r\n" + strings.Repeat("b", parseBufferSize)
+
+	tpl, err := NewTemplateFromReader(iotest.HalfReader(strings.NewReader(template)), "[", "]", BestCompression, WithLineEndings(LF))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	s := decompressBytes(t, tpl.ExecuteBytes(nil))
+	result := strings.Replace(template, "\r\n", "\n", -1)
+	if string(s) != result {
+		t.Fatal("unexpected template value")
+	}
+}
+
+func TestNewWithOptions(t *testing.T) {
+	tpl, err := NewWithOptions("{{foo}}[bar]")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if tpl.level != DefaultCompression {
+		t.Fatalf("unexpected level %d. Expected %d", tpl.level, DefaultCompression)
+	}
+
+	s := decompressBytes(t, tpl.ExecuteBytes(map[string]interface{}{"foo": "111"}))
+	if string(s) != "111[bar]" {
+		t.Fatalf("unexpected template value %q. Expected %q", s, "111[bar]")
+	}
+
+	tpl, err = NewWithOptions("{{foo}}[bar]", WithDelims("[", "]"), WithLevel(BestSpeed))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if tpl.level != BestSpeed {
+		t.Fatalf("unexpected level %d. Expected %d", tpl.level, BestSpeed)
+	}
+
+	s = decompressBytes(t, tpl.ExecuteBytes(map[string]interface{}{"bar": "222"}))
+	if string(s) != "{{foo}}222" {
+		t.Fatalf("unexpected template value %q. Expected %q", s, "{{foo}}222")
+	}
+}
+
+func TestWithStrict(t *testing.T) {
+	tpl, err := NewWithOptions("{{foo}}{{bar|x}}{{#if baz}}{{#end}}", WithStrict())
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if err := tpl.Execute(ioutil.Discard, map[string]interface{}{"foo": "111"}); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	err = tpl.Execute(ioutil.Discard, nil)
+
+	var me *MissingTagError
+	if !errors.As(err, &me) {
+		t.Fatalf("expected *MissingTagError, got %#v", err)
+	}
+	if me.Tag != "foo" {
+		t.Fatalf("unexpected missing tag %q. Expected %q", me.Tag, "foo")
+	}
+
+	if _, err := tpl.ExecuteBytesErr(map[string]interface{}{"foo": nil}); !errors.As(err, &me) {
+		t.Fatalf("expected *MissingTagError for nil value, got %#v", err)
+	}
+
+	tpl.SetDefault("foo", "111")
+	if err := tpl.Execute(ioutil.Discard, nil); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+}
+
+func TestWithMissingHandler(t *testing.T) {
+	tpl := New("[foo]-[bar]", "[", "]", BestCompression, WithMissingHandler(func(w io.Writer, tag string) error {
+		_, err := io.WriteString(w, "<missing "+tag+">")
+		return err
+	}))
+
+	s := decompressBytes(t, tpl.ExecuteBytes(map[string]interface{}{"foo": "111"}))
+	result := "111-<missing bar>"
+	if string(s) != result {
+		t.Fatalf("unexpected template value %q. Expected %q", s, result)
+	}
+}
+
+func TestWithLimits(t *testing.T) {
+	limits := Limits{
+		MaxSize:      64,
+		MaxTags:      3,
+		MaxTagLength: 8,
+	}
+
+	for _, test := range []struct {
+		template, limit string
+	}{
+		{strings.Repeat("x", 65), "MaxSize"},
+		{"[a][b][c][d]", "MaxTags"},
+		{"[abcdefghi]", "MaxTagLength"},
+		{"[abcdefghi", "MaxTagLength"},
+	} {
+		_, err := NewTemplate(test.template, "[", "]", BestCompression, WithLimits(limits))
+
+		var le *LimitError
+		if !errors.As(err, &le) {
+			t.Fatalf("expected *LimitError for %q, got %#v", test.template, err)
+		}
+		if le.Limit != test.limit {
+			t.Fatalf("unexpected limit %q exceeded by %q. Expected %q", le.Limit, test.template, test.limit)
+		}
+	}
+
+	for _, template := range []string{
+		strings.Repeat("x", 64),
+		"[a][b][# a long comment #]",
+		"[abcdefgh]",
+	} {
+		if _, err := NewTemplate(template, "[", "]", BestCompression, WithLimits(limits)); err != nil {
+			t.Fatalf("unexpected error for %q: %s", template, err)
+		}
+	}
+}
+
+func TestWithLimitsOutput(t *testing.T) {
+	tpl := New("foo[bar]baz", "[", "]", BestCompression, WithLimits(Limits{
+		MaxUncompressedOutput: 1 << 10,
+	}))
+
+	if _, err := tpl.ExecuteBytesErr(map[string]interface{}{"bar": "111"}); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	// A TagFunc that never stops writing must be cut off.
+	_, err := tpl.ExecuteFuncBytesErr(func(w io.Writer, tag string) error {
+		for {
+			if _, err := io.WriteString(w, "runaway"); err != nil {
+				return err
+			}
+		}
+	})
+
+	var le *LimitError
+	if !errors.As(err, &le) || le.Limit != "MaxUncompressedOutput" {
+		t.Fatalf("expected *LimitError for MaxUncompressedOutput, got %#v", err)
+	}
+
+	random := make([]byte, 1<<14)
+	rand.New(rand.NewSource(1)).Read(random)
+
+	tpl = New("foo[bar]baz", "[", "]", BestCompression, WithLimits(Limits{
+		MaxOutput: 1 << 10,
+	}))
+
+	_, err = tpl.ExecuteBytesErr(map[string]interface{}{"bar": random})
+	if !errors.As(err, &le) || le.Limit != "MaxOutput" {
+		t.Fatalf("expected *LimitError for MaxOutput, got %#v", err)
+	}
+
+	tpl = New(strings.Repeat("x", 1<<11), "[", "]", BestCompression, WithLimits(Limits{
+		MaxUncompressedOutput: 1 << 10,
+	}))
+
+	_, err = tpl.ExecuteBytesErr(nil)
+	if !errors.As(err, &le) || le.Limit != "MaxUncompressedOutput" {
+		t.Fatalf("expected *LimitError for MaxUncompressedOutput, got %#v", err)
+	}
+}
+
+func TestWithCaseInsensitive(t *testing.T) {
+	tpl := New("[Foo] [bar] [#range Items][Name][#end] [baz]", "[", "]", BestCompression, WithCaseInsensitive())
+
+	m := map[string]interface{}{
+		"foo":   "1",
+		"BAR":   "2",
+		"bar":   "3",
+		"items": []map[string]interface{}{{"name": "a"}, {"NAME": "b"}},
+	}
+	s := decompressBytes(t, tpl.ExecuteBytes(m))
+	result := "1 3 ab "
+	if string(s) != result {
+		t.Fatalf("unexpected template value %q. Expected %q", s, result)
+	}
+
+	if err := tpl.ValidateExact(map[string]interface{}{"FOO": "", "Bar": "", "BAZ": ""}); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	s = decompressBytes(t, New("[Foo]", "[", "]", BestCompression).ExecuteBytes(m))
+	if len(s) != 0 {
+		t.Fatalf("unexpected template value %q. Expected %q", s, "")
+	}
+}
+
+func TestWithMinifyHTML(t *testing.T) {
+	template := "<html>\n\t<body>\n\t\t<p>  [foo]  and   more </p>\n\t\t<PRE>  keep\n   this </pre>\n" +
+		"\t\t<script>  if (a  <  b) {\n    go() }  [bar]</script >  <style>p  { }</style>\r\n\t</body>\n</html>\n"
+	tpl := New(template, "[", "]", BestCompression, WithMinifyHTML())
+
+	s := decompressBytes(t, tpl.ExecuteBytes(map[string]interface{}{"foo": "  x  ", "bar": "1"}))
+	result := "<html>\n<body>\n<p> " + "  x  " + " and more </p>\n<PRE>  keep\n   this </pre>\n" +
+		"<script>  if (a  <  b) {\n    go() }  1</script > <style>p  { }</style>\n</body>\n</html>\n"
+	if string(s) != result {
+		t.Fatalf("unexpected template value %q. Expected %q", s, result)
+	}
+}
+
+func TestWithConstants(t *testing.T) {
+	template := `<link href="/app.css?v=[version]">[name] [title|html] [missing|def] [#range items][version][#end]`
+	tpl := New(template, "[", "]", BestCompression, WithConstants(map[string]interface{}{
+		"version": "abc123",
+		"title":   "<Home>",
+		"missing": "set",
+	}))
+
+	var values int
+	for _, tag := range tpl.tags {
+		if tag.op == opValue {
+			values++
+		}
+	}
+	if values != 1 {
+		t.Fatalf("unexpected number of value tags %d. Expected %d", values, 1)
+	}
+
+	s := decompressBytes(t, tpl.ExecuteBytes(map[string]interface{}{
+		"name":    "x",
+		"version": "ignored",
+		"items":   []map[string]interface{}{{}, {}},
+	}))
+	result := `<link href="/app.css?v=abc123">x &lt;Home&gt; set abc123abc123`
+	if string(s) != result {
+		t.Fatalf("unexpected template value %q. Expected %q", s, result)
+	}
+}
+
+func TestWithParseHook(t *testing.T) {
+	hook := WithParseHook(func(tag string) (string, error) {
+		if strings.HasPrefix(tag, "_") {
+			return "", fmt.Errorf("tag %q is private", tag)
+		}
+
+		return "app." + strings.ToLower(tag), nil
+	})
+
+	tpl := New("[Name] [#if Admin]admin[#end][#range Items][ID][#end] [Title|html]", "[", "]", BestCompression, hook,
+		WithEscaping(map[string]Escaping{"app.name": EscapeHTML}))
+
+	s := decompressBytes(t, tpl.ExecuteBytes(map[string]interface{}{
+		"app": map[string]interface{}{
+			"name":  "<b>",
+			"admin": true,
+			"title": "<i>",
+		},
+		"app.items": []map[string]interface{}{{"app.id": "1"}, {"app.id": "2"}},
+	}))
+	result := "&lt;b&gt; admin12 &lt;i&gt;"
+	if string(s) != result {
+		t.Fatalf("unexpected template value %q. Expected %q", s, result)
+	}
+
+	for _, template := range []string{"[_secret]", "[#if _secret][#end]", "[#range _secret][#end]"} {
+		_, err := NewTemplate(template, "[", "]", BestCompression, hook)
+		if err == nil || !strings.Contains(err.Error(), "is private") {
+			t.Fatalf("expected hook error for %q, got %v", template, err)
+		}
+	}
+}
+
+func TestOnMissing(t *testing.T) {
+	tpl := New("[foo]-[bar]-[#if baz]x[#end]", "[", "]", BestCompression, WithStrict())
+
+	var missing []string
+	tpl.OnMissing(func(w io.Writer, tag string) error {
+		missing = append(missing, tag)
+		_, err := io.WriteString(w, "?")
+		return err
+	})
+
+	s := decompressBytes(t, tpl.ExecuteBytes(map[string]interface{}{"foo": "111"}))
+	result := "111-?-"
+	if string(s) != result {
+		t.Fatalf("unexpected template value %q. Expected %q", s, result)
+	}
+	if len(missing) != 1 || missing[0] != "bar" {
+		t.Fatalf("unexpected missing tags %q. Expected %q", missing, []string{"bar"})
+	}
+
+	tpl.OnMissing(nil)
+	s = decompressBytes(t, tpl.ExecuteBytes(nil))
+	if string(s) != "--" {
+		t.Fatalf("unexpected template value %q. Expected %q", s, "--")
+	}
+}
+
+func TestWithPanicOnError(t *testing.T) {
+	tpl := New("[foo]", "[", "]", BestCompression, WithPanicOnError())
+
+	expectPanic(t, func() {
+		tpl.Execute(ioutil.Discard, map[string]interface{}{"foo": []int{123}})
+	})
+	expectPanic(t, func() {
+		tpl.ExecuteFunc(ioutil.Discard, func(w io.Writer, tag string) error {
+			return errors.New("tag error")
+		})
+	})
+
+	if err := tpl.Execute(ioutil.Discard, map[string]interface{}{"foo": "111"}); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	fixed := New("[foo]", "[", "]", BestCompression, WithPanicOnError(), WithFixedWidths(map[string]int{"foo": 3}))
+	expectPanic(t, func() {
+		fixed.Execute(ioutil.Discard, map[string]interface{}{"foo": "1"})
+	})
+}
+
+func TestMust(t *testing.T) {
+	if tpl := Must(NewTemplate("[foo]", "[", "]", BestCompression)); tpl == nil {
+		t.Fatal("unexpected nil template")
+	}
+
+	expectPanic(t, func() {
+		Must(NewTemplate("[foo", "[", "]", BestCompression))
+	})
+}
+
+func TestWithLimitsOutputEntryPoints(t *testing.T) {
+	random := make([]byte, 1<<14)
+	rand.New(rand.NewSource(1)).Read(random)
+	m := map[string]interface{}{"bar": random}
+
+	for _, limits := range []Limits{
+		{MaxOutput: 1 << 10},
+		{MaxUncompressedOutput: 1 << 10},
+	} {
+		tpl := New("foo[bar]baz", "[", "]", BestCompression, WithLimits(limits))
+
+		for name, execute := range map[string]func() error{
+			"ExecuteBytes": func() (err error) {
+				defer func() {
+					if r := recover(); r != nil {
+						err = fmt.Errorf("%v", r)
+					}
+				}()
+
+				tpl.ExecuteBytes(m)
+				return nil
+			},
+			"ExecuteAppend": func() error {
+				_, err := tpl.ExecuteAppend(nil, m)
+				return err
+			},
+			"ExecuteBudget": func() error {
+				return tpl.ExecuteBudget(ioutil.Discard, m, 0)
+			},
+			"ExecuteFlush": func() error {
+				return tpl.ExecuteFlush(&flushRecorder{}, m)
+			},
+			"ExecuteParallel": func() error {
+				return tpl.ExecuteParallel(ioutil.Discard, m)
+			},
+			"ExecuteDual": func() error {
+				return tpl.ExecuteDual(ioutil.Discard, ioutil.Discard, m)
+			},
+			"ExecuteMapped": func() error {
+				_, err := tpl.ExecuteMapped(ioutil.Discard, m)
+				return err
+			},
+			"ExecuteSplit": func() error {
+				_, err := tpl.ExecuteSplit(1<<12, func(int) (io.WriteCloser, error) {
+					return nopWriteCloser{ioutil.Discard}, nil
+				}, m)
+				return err
+			},
+		} {
+			if err := execute(); err == nil || !strings.Contains(err.Error(), "template exceeds") {
+				t.Errorf("%s with %+v: expected limit error, got %v", name, limits, err)
+			}
+		}
+
+		if limits.MaxUncompressedOutput != 0 {
+			if err := tpl.ExecutePlain(ioutil.Discard, m); err == nil {
+				t.Errorf("ExecutePlain with %+v: expected limit error", limits)
+			}
+		}
+	}
+}
@@ -0,0 +1,129 @@
+package gziptemplate
+
+import (
+	"io"
+	"runtime"
+	"sync"
+
+	"go.tmthrgd.dev/gzipbuilder"
+)
+
+// ExecuteFuncParallel is like ExecuteFunc but compresses tag values
+// concurrently.
+//
+// f is called on each tag occurrence in order, as with ExecuteFunc, but the
+// values it writes are buffered. Each run of values between static segments
+// is then compressed in its own goroutine as an independent deflate segment
+// and the segments are stitched into a single gzip stream. This spreads the
+// cost of large values, such as embedded JSON state, across cores at the
+// expense of holding them in memory and a slightly larger output.
+func (t *Template) ExecuteFuncParallel(w io.Writer, f TagFunc) error {
+	t.stats.executed()
+
+	if len(t.tags) == 0 {
+		if err := t.checkOutput(t.template); err != nil {
+			return err
+		}
+
+		_, err := w.Write(t.template)
+		return err
+	}
+
+	w, ol := t.limitOutput(w)
+
+	var pw parallelWriter
+	if err := t.execute(stream{add: pw.add, w: &pw}.withLimit(ol), f); err != nil {
+		return err
+	}
+
+	if err := pw.compress(t.level); err != nil {
+		return err
+	}
+
+	gw := gzipbuilder.NewWriter(w, t.level)
+	for _, part := range pw.parts {
+		gw.AddPrecompressedData(part.d)
+	}
+
+	return gw.Close()
+}
+
+// ExecuteParallel is like Execute but compresses tag values concurrently.
+//
+// See ExecuteFuncParallel for details.
+func (t *Template) ExecuteParallel(w io.Writer, m map[string]interface{}) error {
+	return t.ExecuteFuncParallel(w, t.mapTagFunc(m))
+}
+
+// parallelWriter collects the precompressed static segments added to it and
+// the values written to it, in order, for compress.
+type parallelWriter struct {
+	parts []parallelPart
+}
+
+// parallelPart is either a precompressed segment, d, or the uncompressed
+// values, b, written between two segments.
+type parallelPart struct {
+	d *gzipbuilder.PrecompressedData
+	b []byte
+}
+
+func (pw *parallelWriter) add(d *gzipbuilder.PrecompressedData) {
+	pw.parts = append(pw.parts, parallelPart{d: d})
+}
+
+func (pw *parallelWriter) Write(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+
+	if n := len(pw.parts); n == 0 || pw.parts[n-1].d != nil {
+		pw.parts = append(pw.parts, parallelPart{})
+	}
+
+	part := &pw.parts[len(pw.parts)-1]
+	part.b = append(part.b, p...)
+	return len(p), nil
+}
+
+// compress compresses each run of values at level, with at most GOMAXPROCS
+// running at once.
+func (pw *parallelWriter) compress(level int) error {
+	var (
+		wg  sync.WaitGroup
+		sem = make(chan struct{}, runtime.GOMAXPROCS(0))
+
+		errOnce sync.Once
+		err     error
+	)
+
+	for i := range pw.parts {
+		part := &pw.parts[i]
+		if part.d != nil {
+			continue
+		}
+
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+
+			w := gzipbuilder.NewPrecompressedWriter(level)
+			w.Write(part.b)
+
+			d, derr := w.Data()
+			if derr != nil {
+				errOnce.Do(func() { err = derr })
+				return
+			}
+
+			part.d, part.b = d, nil
+		}()
+	}
+
+	wg.Wait()
+	return err
+}
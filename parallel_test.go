@@ -0,0 +1,49 @@
+package gziptemplate
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestExecuteParallel(t *testing.T) {
+	set := NewTemplateSet()
+	if _, err := set.Parse("item", "<li>[name]</li>", "[", "]", BestSpeed); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	tpl, err := set.Parse("page", `<ul>[#range items][#include "item"][#end]</ul>[state][empty]`, "[", "]", BestCompression)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	m := map[string]interface{}{
+		"items": []map[string]interface{}{{"name": "a"}, {"name": "b"}, {"name": "c"}},
+		"state": strings.Repeat(`{"key":"value"},`, 1<<12),
+	}
+
+	var expect, buf bytes.Buffer
+	if err := tpl.Execute(&expect, m); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if err := tpl.ExecuteParallel(&buf, m); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	s := decompressBytes(t, buf.Bytes())
+	if e := decompressBytes(t, expect.Bytes()); !bytes.Equal(s, e) {
+		t.Fatalf("unexpected template value %q. Expected %q", s, e)
+	}
+}
+
+func TestExecuteParallelNoTags(t *testing.T) {
+	tpl := New("foobar", "[", "]", BestCompression)
+
+	var buf bytes.Buffer
+	if err := tpl.ExecuteParallel(&buf, nil); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if s := decompressBytes(t, buf.Bytes()); string(s) != "foobar" {
+		t.Fatalf("unexpected template value %q. Expected %q", s, "foobar")
+	}
+}
@@ -0,0 +1,56 @@
+package gziptemplate
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ParseError is returned by NewTemplate and the other constructors for
+// syntax errors in a template. It can be detected with errors.As.
+type ParseError struct {
+	// Offset is the byte offset in the template source of the tag at
+	// fault. Line and Column give the same position, numbered from one;
+	// Column counts bytes.
+	Offset int64
+	Line   int
+	Column int
+
+	Err error
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("%s at line %d, column %d", e.Err, e.Line, e.Column)
+}
+
+// Unwrap returns the underlying error.
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// position is a position in the template source.
+type position struct {
+	Offset int64
+	Line   int
+	Column int
+}
+
+// errorf returns a *ParseError at p.
+func (p position) errorf(format string, args ...interface{}) error {
+	return p.wrap(fmt.Errorf(format, args...))
+}
+
+// wrap returns err as a *ParseError at p, unless it already is one.
+func (p position) wrap(err error) error {
+	var pe *ParseError
+	if errors.As(err, &pe) {
+		return err
+	}
+
+	return &ParseError{
+		Offset: p.Offset,
+		Line:   p.Line,
+		Column: p.Column,
+
+		Err: err,
+	}
+}
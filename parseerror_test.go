@@ -0,0 +1,36 @@
+package gziptemplate
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseError(t *testing.T) {
+	for _, tc := range []struct {
+		template     string
+		offset       int64
+		line, column int
+		msg          string
+	}{
+		{"foo\nbar [baz", 8, 2, 5, `gziptemplate: missing end tag="]" at line 2, column 5`},
+		{"a\nb\n  [#if x]\n[#range y]z[#end]", 6, 3, 3, "gziptemplate: missing #end for conditional section at line 3, column 3"},
+		{"[a]\n[b]\n[#else]", 8, 3, 1, "gziptemplate: #else outside of conditional section at line 3, column 1"},
+		{"x [# comment ] more\n", 2, 1, 3, "gziptemplate: unterminated comment at line 1, column 3"},
+	} {
+		_, err := NewTemplate(tc.template, "[", "]", BestSpeed)
+
+		var pe *ParseError
+		if !errors.As(err, &pe) {
+			t.Errorf("%q: expected *ParseError, got %#v", tc.template, err)
+			continue
+		}
+
+		if pe.Offset != tc.offset || pe.Line != tc.line || pe.Column != tc.column {
+			t.Errorf("%q: unexpected position %d (%d:%d). Expected %d (%d:%d)",
+				tc.template, pe.Offset, pe.Line, pe.Column, tc.offset, tc.line, tc.column)
+		}
+		if err.Error() != tc.msg {
+			t.Errorf("%q: unexpected error %q. Expected %q", tc.template, err, tc.msg)
+		}
+	}
+}
@@ -0,0 +1,886 @@
+package gziptemplate
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// parseBufferSize is the minimum size of the buffer used to read templates.
+const parseBufferSize = 4096
+
+// parser splits a template read from r into static text and tags.
+type parser struct {
+	r *bufio.Reader
+	b *TemplateBuilder
+
+	startTag []byte
+	endTag   []byte
+
+	options
+
+	// sections holds the state of each enclosing conditional section.
+	sections []section
+
+	lineEndings lineEndingFilter
+	minifier    *htmlMinifier
+
+	// context, if non-nil, tracks the HTML context of the static text for
+	// WithAutoEscape.
+	context *htmlContext
+
+	// space holds trailing whitespace of the text so far, which a
+	// following trim marker removes. trim reports whether leading
+	// whitespace of the text that follows is to be removed.
+	space []byte
+	trim  bool
+
+	// offset is the number of bytes of the source consumed so far, line
+	// the number of newlines among them and lineStart the offset at which
+	// the current line begins.
+	offset    int64
+	line      int
+	lineStart int64
+
+	// delim is the position of the last delimiter found by scan.
+	delim position
+
+	// textPos and tagPos hold the source offsets of the emitted segments
+	// and tags.
+	textPos []int64
+	tagPos  []int64
+
+	// base is the template being extended, if any, and defines holds the
+	// blocks defined to override its own.
+	base    *Template
+	defines map[string]*Template
+}
+
+// section is the state of a conditional section resolved at compile time.
+type section struct {
+	// emit reports whether the current branch is being emitted.
+	emit bool
+
+	// parentEmit reports whether the enclosing branch is being emitted.
+	parentEmit bool
+
+	sawElse bool
+
+	// runtime reports whether the section is resolved at execution time,
+	// in which case tag is the index of its last control tag.
+	runtime bool
+	tag     int
+
+	// loop reports whether the section is repeated.
+	loop bool
+
+	// block reports whether the section is a block that may be
+	// overridden by templates extending this one.
+	block bool
+
+	// define, if non-nil, holds the state of the enclosing template while
+	// the definition of a block is parsed.
+	define *defineState
+
+	// pos is the position of the tag that opened the section.
+	pos position
+}
+
+// defineState is the state of a template while the definition of one of its
+// blocks is parsed.
+type defineState struct {
+	name string
+
+	b       *TemplateBuilder
+	tagPos  []int64
+	textPos []int64
+}
+
+// parse parses the template read from r. source, if non-nil, is the
+// template already held in memory, which is kept rather than a copy.
+func parse(r io.Reader, source *string, startTag, endTag string, level int, opts []Option) (*Template, error) {
+	if len(startTag) == 0 {
+		panic("gziptemplate: startTag cannot be empty")
+	}
+	if len(endTag) == 0 {
+		panic("gziptemplate: endTag cannot be empty")
+	}
+
+	size := parseBufferSize
+	if n := 2 * len(startTag); n > size {
+		size = n
+	}
+	if n := 2 * len(endTag); n > size {
+		size = n
+	}
+
+	p := &parser{
+		b: NewTemplateBuilder(level),
+
+		startTag: []byte(startTag),
+		endTag:   []byte(endTag),
+	}
+	for _, opt := range opts {
+		opt(&p.options)
+	}
+	p.lineEndings.mode = p.lineEnding
+	if p.minify {
+		p.minifier = new(htmlMinifier)
+	}
+	if p.autoEscape {
+		p.context = new(htmlContext)
+	}
+
+	if p.limits.MaxSize > 0 {
+		r = &sizeLimitReader{r: r, max: p.limits.MaxSize}
+	}
+
+	// Streamed sources are only kept when asked for, so that they don't
+	// defeat parsing in bounded memory.
+	var copied strings.Builder
+	if source == nil && p.keepSource && !p.dropSource {
+		r = io.TeeReader(r, &copied)
+	}
+	p.r = bufio.NewReaderSize(r, size)
+
+	if err := p.parse(); err != nil {
+		return nil, err
+	}
+
+	var (
+		t   *Template
+		err error
+	)
+	if p.base != nil {
+		t, err = extend(p.base, p.defines)
+	} else {
+		t, err = p.template()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	t.startTag, t.endTag = startTag, endTag
+	switch {
+	case p.dropSource:
+	case source != nil:
+		t.source = *source
+	default:
+		t.source = copied.String()
+	}
+	t.middleware = p.middleware
+	t.missing = p.missing
+	t.foldCase = p.foldCase
+	t.panics = p.panics
+	t.limits = p.limits
+	t.observer = p.observer
+	t.separator = p.separator
+
+	if p.smallest {
+		t.smallest = newSmallestPool(level)
+	}
+
+	if len(p.widths) != 0 && len(t.tags) != 0 {
+		if t.patch, err = newPatchImage(t, p.widths); err != nil {
+			return nil, err
+		}
+	}
+
+	return t, nil
+}
+
+// template completes the template being built.
+func (p *parser) template() (*Template, error) {
+	t, err := p.b.Template()
+	if err != nil {
+		return nil, err
+	}
+
+	t.textPos = p.textPos
+	for i := range t.tags {
+		t.tags[i].pos = p.tagPos[i]
+	}
+
+	return t, nil
+}
+
+func (p *parser) parse() error {
+	p.textPos = append(p.textPos, 0)
+
+	var (
+		tag  bytes.Buffer
+		tags int
+	)
+	for {
+		found, err := p.scan(p.startTag, p.text)
+		if err != nil {
+			return err
+		}
+		if !found {
+			break
+		}
+
+		start := p.delim
+		pos := start.Offset
+
+		// A doubled start tag is a literal start tag, unless it would be
+		// ambiguous with an empty tag.
+		if !bytes.Equal(p.startTag, p.endTag) {
+			if b, _ := p.r.Peek(len(p.startTag)); bytes.Equal(b, p.startTag) {
+				p.discard(len(b))
+				if err := p.text(p.startTag); err != nil {
+					return err
+				}
+				continue
+			}
+		}
+
+		if tags++; p.limits.MaxTags > 0 && tags > p.limits.MaxTags {
+			return start.wrap(&LimitError{"MaxTags", int64(p.limits.MaxTags)})
+		}
+
+		tag.Reset()
+		found, err = p.scan(p.endTag, func(b []byte) error {
+			tag.Write(b)
+			if max := p.limits.MaxTagLength; max > 0 && tag.Len() > max && !isComment(tag.Bytes()) {
+				return start.wrap(&LimitError{"MaxTagLength", int64(max)})
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		if !found {
+			return start.errorf("gziptemplate: missing end tag=%q", p.endTag)
+		}
+
+		if isComment(tag.Bytes()) {
+			// Comments may contain the end tag, only the end tag
+			// preceded by '#' ends them.
+			for !bytes.HasSuffix(tag.Bytes(), []byte("#")) {
+				tag.Write(p.endTag)
+				found, err = p.scan(p.endTag, func(p []byte) error {
+					tag.Write(p)
+					return nil
+				})
+				if err != nil {
+					return err
+				}
+				if !found {
+					return start.errorf("gziptemplate: unterminated comment")
+				}
+			}
+
+			continue
+		}
+
+		n := len(p.sections)
+		if err := p.tag(tag.String(), pos); err != nil {
+			return start.wrap(err)
+		}
+		if len(p.sections) > n {
+			p.sections[len(p.sections)-1].pos = start
+		}
+	}
+
+	if len(p.sections) != 0 {
+		return p.sections[len(p.sections)-1].pos.errorf("gziptemplate: missing #end for conditional section")
+	}
+
+	p.flushSpace()
+	p.flushText()
+	return nil
+}
+
+// writeText adds static text to the template, passing it through the
+// minifier and line ending filter.
+func (p *parser) writeText(b []byte) {
+	if p.context != nil {
+		p.context.write(b)
+	}
+
+	if p.minifier == nil {
+		p.lineEndings.write(b, p.b.AddText)
+		return
+	}
+
+	p.minifier.write(b, func(b []byte) {
+		p.lineEndings.write(b, p.b.AddText)
+	})
+}
+
+// flushText flushes any static text held back by writeText. It must be
+// called at the end of each run of text.
+func (p *parser) flushText() {
+	if p.minifier != nil {
+		p.minifier.flush(func(b []byte) {
+			p.lineEndings.write(b, p.b.AddText)
+		})
+	}
+
+	p.lineEndings.flush(p.b.AddText)
+}
+
+// isComment reports whether the contents of a tag begin a comment, that is
+// '#' followed by whitespace.
+func isComment(tag []byte) bool {
+	if len(tag) < 2 || tag[0] != '#' {
+		return false
+	}
+
+	return isSpace(tag[1])
+}
+
+// spaceChars are the whitespace characters recognised by trim markers and
+// comments.
+const spaceChars = " \t\r\n"
+
+func isSpace(c byte) bool {
+	return strings.IndexByte(spaceChars, c) >= 0
+}
+
+// trimMarkers removes the trim markers from the contents of a tag, reporting
+// whether whitespace before and after the tag is to be removed.
+func trimMarkers(name string) (string, bool, bool) {
+	left := len(name) >= 2 && name[0] == '-' && isSpace(name[1])
+	if left {
+		name = name[2:]
+	}
+
+	right := len(name) >= 2 && name[len(name)-1] == '-' && isSpace(name[len(name)-2])
+	if right {
+		name = name[:len(name)-2]
+	}
+
+	return name, left, right
+}
+
+// emitting reports whether text and tags are currently being emitted.
+func (p *parser) emitting() bool {
+	if len(p.sections) == 0 {
+		// Only blocks are kept from templates that extend another.
+		return p.base == nil
+	}
+
+	return p.sections[len(p.sections)-1].emit
+}
+
+// text handles static text between tags.
+func (p *parser) text(b []byte) error {
+	if !p.emitting() {
+		return nil
+	}
+
+	if p.trim {
+		b = bytes.TrimLeft(b, spaceChars)
+		if len(b) == 0 {
+			return nil
+		}
+		p.trim = false
+	}
+
+	// Hold back trailing whitespace in case a trim marker follows.
+	if i := len(bytes.TrimRight(b, spaceChars)); i > 0 {
+		p.flushSpace()
+		p.writeText(b[:i])
+		b = b[i:]
+	}
+
+	p.space = append(p.space, b...)
+	return nil
+}
+
+// flushSpace emits any held back whitespace.
+func (p *parser) flushSpace() {
+	if len(p.space) != 0 {
+		p.writeText(p.space)
+		p.space = p.space[:0]
+	}
+}
+
+// tag handles the contents of a tag found at offset pos.
+func (p *parser) tag(name string, pos int64) error {
+	src := string(p.startTag) + name + string(p.endTag)
+
+	name, trimLeft, trimRight := trimMarkers(name)
+	if trimLeft {
+		p.space = p.space[:0]
+	} else {
+		p.flushSpace()
+	}
+	p.trim = trimRight
+
+	p.flushText()
+
+	if strings.HasPrefix(name, "#") {
+		return p.directive(name[1:], pos)
+	}
+
+	if !p.emitting() {
+		return nil
+	}
+
+	parts := strings.Split(name, "|")
+	name = parts[0]
+	if len(parts) > 1 {
+		name = strings.TrimSpace(name)
+	}
+
+	name, err := p.tagName(name)
+	if err != nil {
+		return err
+	}
+
+	// Filters are followed by an optional default, which is taken to
+	// begin at the first element that doesn't name a filter.
+	var (
+		filters []escapeFunc
+		def     string
+		hasDef  bool
+	)
+	for i, part := range parts[1:] {
+		fn, ok := p.filter(strings.TrimSpace(part))
+		if !ok {
+			def, hasDef = strings.Join(parts[1+i:], "|"), true
+			break
+		}
+
+		filters = append(filters, fn)
+	}
+
+	policy, ok := p.escaping[name]
+	escape := policy.escapeFunc()
+
+	// quote is written either side of values that WithAutoEscape turns
+	// into string literals, and filterURL is set for values that begin a
+	// URL.
+	var (
+		quote     string
+		filterURL bool
+	)
+	if !ok && p.context != nil {
+		var err error
+		if escape, quote, err = p.context.escape(); err != nil {
+			return err
+		}
+		filterURL = p.context.filtersURL()
+	}
+
+	escape = chainEscape(append(filters, escape)...)
+	if v, ok := p.constants[name]; ok {
+		if filterURL {
+			escape = chainEscape(newURLFilter(), escape)
+		}
+
+		p.writeText([]byte(quote))
+		if err := p.constant(name, escape, v); err != nil {
+			return err
+		}
+		p.writeText([]byte(quote))
+		return p.b.err
+	}
+
+	tg := tag{name: name, escape: escape, filterURL: filterURL, src: src}
+	if !hasDef {
+		p.quotedValue(tg, quote, pos)
+		return p.b.err
+	}
+
+	// The default is compiled as though the tag were written as:
+	//	[#if name][name][#else]def[#end]
+	// except that the condition is whether any value is given.
+	has := p.op(opHas, name, pos)
+	p.quotedValue(tg, quote, pos)
+	els := p.op(opElse, "", pos)
+	p.jump(has, els)
+	p.writeText([]byte(def))
+	p.flushText()
+	p.jump(els, p.op(opEnd, "", pos))
+	return p.b.err
+}
+
+// tagName passes the name of a tag, or of the value of a conditional or
+// repeated section, through the ParseHook, if any.
+func (p *parser) tagName(name string) (string, error) {
+	if p.hook == nil {
+		return name, nil
+	}
+
+	return p.hook(name)
+}
+
+// constant writes the value v of the tag name, given by WithConstants, as
+// static text.
+func (p *parser) constant(name string, escape escapeFunc, v interface{}) error {
+	var buf bytes.Buffer
+	var w io.Writer = &buf
+	if escape != nil {
+		w = &escapeWriter{w: w, escape: escape}
+	}
+
+	if nt, ok := v.(*Template); ok {
+		if err := nt.render(w, nt.mapTagFunc(p.constants)); err != nil {
+			return err
+		}
+	} else if err := writeValue(w, name, v); err != nil {
+		return err
+	}
+
+	p.writeText(buf.Bytes())
+	return p.b.err
+}
+
+// quotedValue emits a tag as with value, with quote as static text either
+// side of it.
+func (p *parser) quotedValue(tg tag, quote string, pos int64) {
+	if quote == "" {
+		p.value(tg, pos)
+		return
+	}
+
+	p.writeText([]byte(quote))
+	p.flushText()
+	p.value(tg, pos)
+	p.writeText([]byte(quote))
+	p.flushText()
+}
+
+// value emits the tag tg found at offset pos that is substituted with a
+// value.
+func (p *parser) value(tg tag, pos int64) {
+	p.b.addTag(tg)
+	if p.b.err == nil {
+		p.tagPos = append(p.tagPos, pos)
+		p.textPos = append(p.textPos, p.offset)
+	}
+}
+
+// filter returns the filter named name.
+func (p *parser) filter(name string) (escapeFunc, bool) {
+	if fn, ok := p.filters[name]; ok {
+		return escapeFunc(fn), true
+	}
+
+	fn, ok := builtinFilters[name]
+	return fn, ok
+}
+
+// directive handles a tag beginning with '#' found at offset pos.
+func (p *parser) directive(d string, pos int64) error {
+	args := strings.Fields(d)
+	if len(args) == 0 {
+		return fmt.Errorf("gziptemplate: invalid directive %q", d)
+	}
+
+	switch args[0] {
+	case "if":
+		if len(args) != 2 {
+			return fmt.Errorf("gziptemplate: #if requires exactly one argument, got %q", d)
+		}
+
+		name, err := p.tagName(args[1])
+		if err != nil {
+			return err
+		}
+
+		emit := p.emitting()
+		flag, ok := p.flags[name]
+		if !ok {
+			s := section{
+				emit:       emit,
+				parentEmit: emit,
+				runtime:    emit,
+			}
+			if emit {
+				s.tag = p.op(opIf, name, pos)
+			}
+
+			p.sections = append(p.sections, s)
+			break
+		}
+
+		p.sections = append(p.sections, section{
+			emit:       emit && flag,
+			parentEmit: emit,
+		})
+	case "range":
+		if len(args) != 2 {
+			return fmt.Errorf("gziptemplate: #range requires exactly one argument, got %q", d)
+		}
+
+		name, err := p.tagName(args[1])
+		if err != nil {
+			return err
+		}
+
+		emit := p.emitting()
+		s := section{
+			emit:       emit,
+			parentEmit: emit,
+			runtime:    emit,
+			loop:       true,
+		}
+		if emit {
+			s.tag = p.op(opRange, name, pos)
+		}
+
+		p.sections = append(p.sections, s)
+	case "include":
+		name, err := strconv.Unquote(strings.TrimSpace(d[len("include"):]))
+		if err != nil {
+			return fmt.Errorf("gziptemplate: #include requires a quoted template name, got %q", d)
+		}
+
+		if !p.emitting() {
+			break
+		}
+
+		t := p.set.Lookup(name)
+		if t == nil {
+			return fmt.Errorf("gziptemplate: unknown template %q in #include", name)
+		}
+
+		p.b.addTemplate(t)
+		if p.b.err != nil {
+			break
+		}
+
+		p.tagPos = append(p.tagPos, pos)
+		for range t.tags {
+			p.tagPos = append(p.tagPos, -1)
+		}
+		p.tagPos = append(p.tagPos, pos)
+
+		for range t.texts {
+			p.textPos = append(p.textPos, -1)
+		}
+		p.textPos = append(p.textPos, p.offset)
+	case "block":
+		if len(args) != 2 {
+			return fmt.Errorf("gziptemplate: #block requires exactly one argument, got %q", d)
+		}
+
+		emit := p.emitting()
+		s := section{
+			emit:       emit,
+			parentEmit: emit,
+			runtime:    emit,
+			block:      true,
+		}
+		if emit {
+			s.tag = p.op(opBlock, args[1], pos)
+		}
+
+		p.sections = append(p.sections, s)
+	case "extends":
+		name, err := strconv.Unquote(strings.TrimSpace(d[len("extends"):]))
+		if err != nil {
+			return fmt.Errorf("gziptemplate: #extends requires a quoted template name, got %q", d)
+		}
+		if p.base != nil || len(p.sections) != 0 {
+			return errors.New("gziptemplate: #extends must appear once outside of any section")
+		}
+
+		if p.base = p.set.Lookup(name); p.base == nil {
+			return fmt.Errorf("gziptemplate: unknown template %q in #extends", name)
+		}
+		p.defines = make(map[string]*Template)
+	case "define":
+		if len(args) != 2 {
+			return fmt.Errorf("gziptemplate: #define requires exactly one argument, got %q", d)
+		}
+		if p.base == nil || len(p.sections) != 0 {
+			return errors.New("gziptemplate: #define must follow #extends outside of any section")
+		}
+		if _, dup := p.defines[args[1]]; dup {
+			return fmt.Errorf("gziptemplate: duplicate #define %q", args[1])
+		}
+
+		p.sections = append(p.sections, section{
+			emit: true,
+			define: &defineState{
+				name: args[1],
+
+				b:       p.b,
+				tagPos:  p.tagPos,
+				textPos: p.textPos,
+			},
+		})
+
+		p.b = NewTemplateBuilder(p.b.level)
+		p.tagPos = nil
+		p.textPos = []int64{p.offset}
+	case "flush":
+		if len(args) != 1 {
+			return fmt.Errorf("gziptemplate: #flush takes no arguments, got %q", d)
+		}
+
+		// A no-op tag ends the static segment, which ExecuteFlush
+		// flushes.
+		if p.emitting() {
+			p.op(opNop, "", pos)
+		}
+	case "raw":
+		if len(args) != 1 {
+			return fmt.Errorf("gziptemplate: #raw takes no arguments, got %q", d)
+		}
+
+		// Everything up to the closing tag, which must be written
+		// exactly, is static text.
+		end := make([]byte, 0, len(p.startTag)+len("#endraw")+len(p.endTag))
+		end = append(end, p.startTag...)
+		end = append(end, "#endraw"...)
+		end = append(end, p.endTag...)
+
+		found, err := p.scan(end, p.text)
+		if err != nil {
+			return err
+		}
+		if !found {
+			return errors.New("gziptemplate: missing #endraw for raw section")
+		}
+	case "else":
+		if len(p.sections) == 0 {
+			return errors.New("gziptemplate: #else outside of conditional section")
+		}
+
+		s := &p.sections[len(p.sections)-1]
+		if s.loop {
+			return errors.New("gziptemplate: #else in #range section")
+		}
+		if s.block || s.define != nil {
+			return errors.New("gziptemplate: #else in #block or #define section")
+		}
+		if s.sawElse {
+			return errors.New("gziptemplate: duplicate #else in conditional section")
+		}
+
+		s.sawElse = true
+		if s.runtime {
+			i := p.op(opElse, "", pos)
+			p.jump(s.tag, i)
+			s.tag = i
+			break
+		}
+
+		s.emit = s.parentEmit && !s.emit
+	case "end":
+		if len(p.sections) == 0 {
+			return errors.New("gziptemplate: #end outside of conditional section")
+		}
+
+		s := p.sections[len(p.sections)-1]
+		p.sections = p.sections[:len(p.sections)-1]
+		switch {
+		case s.define != nil:
+			return p.endDefine(s.define)
+		case s.block:
+			if s.runtime {
+				p.jump(s.tag, p.op(opNop, "", pos))
+			}
+		case s.runtime:
+			p.jump(s.tag, p.op(opEnd, "", pos))
+		}
+	default:
+		return fmt.Errorf("gziptemplate: unknown directive %q", args[0])
+	}
+
+	return p.b.err
+}
+
+// endDefine completes the definition of a block and restores the state of
+// the enclosing template.
+func (p *parser) endDefine(d *defineState) error {
+	t, err := p.template()
+	if err != nil {
+		return err
+	}
+
+	p.defines[d.name] = t
+	p.b, p.tagPos, p.textPos = d.b, d.tagPos, d.textPos
+	return nil
+}
+
+// op emits a control tag found at offset pos and returns its index.
+func (p *parser) op(op tagOp, name string, pos int64) int {
+	i := p.b.addOp(op, name)
+	if i >= 0 {
+		p.tagPos = append(p.tagPos, pos)
+		p.textPos = append(p.textPos, p.offset)
+	}
+	return i
+}
+
+// jump sets the control tag i to continue after the tag j when its branch
+// is not taken.
+func (p *parser) jump(i, j int) {
+	if i >= 0 && j >= 0 {
+		p.b.tags[i].jump = j
+	}
+}
+
+// scan reads until delim, passing everything before it to emit in one or
+// more chunks. The chunks are only valid for the duration of the call. scan
+// reports whether delim was found before the end of the input.
+func (p *parser) scan(delim []byte, emit func([]byte) error) (bool, error) {
+	for {
+		buf, err := p.r.Peek(p.r.Size())
+		if i := bytes.Index(buf, delim); i >= 0 {
+			if err := emit(buf[:i]); err != nil {
+				return false, err
+			}
+
+			p.discard(i)
+			p.delim = p.position()
+			p.discard(len(delim))
+			return true, nil
+		}
+
+		switch err {
+		case nil:
+		case io.EOF:
+			if err := emit(buf); err != nil {
+				return false, err
+			}
+
+			p.discard(len(buf))
+			return false, nil
+		default:
+			return false, err
+		}
+
+		// Hold back enough to match a delimiter that straddles the end
+		// of the buffer.
+		n := len(buf) - len(delim) + 1
+		if err := emit(buf[:n]); err != nil {
+			return false, err
+		}
+
+		p.discard(n)
+	}
+}
+
+// discard skips the next n bytes of the source.
+func (p *parser) discard(n int) {
+	b, _ := p.r.Peek(n)
+	if i := bytes.LastIndexByte(b, '\n'); i >= 0 {
+		p.line += bytes.Count(b[:i+1], []byte("\n"))
+		p.lineStart = p.offset + int64(i) + 1
+	}
+
+	p.r.Discard(n)
+	p.offset += int64(n)
+}
+
+// position returns the position of the next byte of the source.
+func (p *parser) position() position {
+	return position{
+		Offset: p.offset,
+		Line:   p.line + 1,
+		Column: int(p.offset-p.lineStart) + 1,
+	}
+}
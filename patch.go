@@ -0,0 +1,236 @@
+package gziptemplate
+
+import (
+	"bytes"
+	"compress/flate"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"time"
+)
+
+// patchImage is the complete gzip output of a template whose tags all have a
+// fixed width. Each tag value occupies a stored deflate block whose contents
+// are overwritten at execution time.
+type patchImage struct {
+	image []byte
+
+	// offsets and widths locate the value of each tag within image.
+	offsets []int
+	widths  []int
+
+	// textCRCs holds the CRC-32 of each static segment.
+	textCRCs []uint32
+
+	size uint32
+}
+
+// tagWidths returns the widths of the tags of t, for which p was built, by
+// name, as given to newPatchImage. It is used to patch templates derived
+// from t.
+func (p *patchImage) tagWidths(t *Template) map[string]int {
+	widths := make(map[string]int, len(t.tags))
+	for i, tag := range t.tags {
+		widths[tag.name] = p.widths[i]
+	}
+	return widths
+}
+
+// newPatchImage builds a patchImage for t. It returns nil if any tag of t
+// lacks a width.
+func newPatchImage(t *Template, widths map[string]int) (*patchImage, error) {
+	p := &patchImage{
+		offsets: make([]int, len(t.tags)),
+		widths:  make([]int, len(t.tags)),
+	}
+
+	for i, tag := range t.tags {
+		width, ok := widths[tag.name]
+		if !ok || tag.op != opValue {
+			return nil, nil
+		}
+		if width <= 0 || width > 0xffff {
+			return nil, fmt.Errorf("gziptemplate: invalid width %d for tag=%q", width, tag.name)
+		}
+
+		p.widths[i] = width
+	}
+
+	texts, err := t.plainTexts()
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	buf.Write([]byte{0x1f, 0x8b, 8, 0, 0, 0, 0, 0, 0, 0xff})
+
+	fw, err := flate.NewWriter(&buf, t.level)
+	if err != nil {
+		return nil, err
+	}
+
+	var size int
+	for i, text := range texts {
+		// Each segment is compressed independently, as back-references
+		// must not reach across the values.
+		fw.Reset(&buf)
+		if _, err := fw.Write(text); err != nil {
+			return nil, err
+		}
+		if err := fw.Flush(); err != nil {
+			return nil, err
+		}
+
+		p.textCRCs = append(p.textCRCs, crc32.ChecksumIEEE(text))
+		size += len(text)
+
+		if i == len(t.tags) {
+			break
+		}
+
+		// A stored block header: BFINAL=0 and BTYPE=00 padded to a
+		// byte, followed by LEN and NLEN.
+		width := uint16(p.widths[i])
+		buf.WriteByte(0)
+		binary.Write(&buf, binary.LittleEndian, [2]uint16{width, ^width})
+
+		p.offsets[i] = buf.Len()
+		buf.Write(make([]byte, width))
+		size += int(width)
+	}
+
+	// An empty final stored block, then space for the trailer.
+	buf.Write([]byte{1, 0, 0, 0xff, 0xff})
+	buf.Write(make([]byte, 8))
+
+	p.image = buf.Bytes()
+	p.size = uint32(size)
+	return p, nil
+}
+
+// execute renders t, calling f on each tag occurrence, and returns the gzip
+// output.
+func (p *patchImage) execute(t *Template, f TagFunc) ([]byte, error) {
+	if len(t.middleware) != 0 {
+		f = Chain(f, t.middleware...)
+	}
+
+	b := append([]byte(nil), p.image...)
+
+	crc := p.textCRCs[0]
+	for i, tag := range t.tags {
+		value := b[p.offsets[i] : p.offsets[i]+p.widths[i]]
+
+		fw := &fixedWriter{b: value}
+		var w io.Writer = fw
+		if escape := tag.escaper(); escape != nil {
+			w = &escapeWriter{w: w, escape: escape}
+		}
+
+		var start time.Time
+		if t.observer != nil {
+			start = time.Now()
+		}
+
+		t.stats.rendered(i)
+		err := f(w, tag.name)
+		if err == errVerbatim {
+			err = t.writeSource(fw, i)
+		}
+		if t.observer != nil {
+			t.observer.Observe(tag.name, int64(fw.n), time.Since(start), err)
+		}
+		if err != nil {
+			return nil, t.tagError(i, err)
+		}
+		if fw.n != len(value) {
+			return nil, fmt.Errorf("gziptemplate: value for tag=%q is %d bytes wide, expected %d", tag.name, fw.n, len(value))
+		}
+
+		crc = crc32Combine(crc, crc32.ChecksumIEEE(value), int64(len(value)))
+		crc = crc32Combine(crc, p.textCRCs[i+1], t.textLens[i+1])
+	}
+
+	trailer := b[len(b)-8:]
+	binary.LittleEndian.PutUint32(trailer, crc)
+	binary.LittleEndian.PutUint32(trailer[4:], p.size)
+	return b, nil
+}
+
+// errWidth is returned by fixedWriter when a value is too wide.
+var errWidth = errors.New("gziptemplate: value exceeds fixed width")
+
+// fixedWriter writes into a fixed-size slice.
+type fixedWriter struct {
+	b []byte
+	n int
+}
+
+func (w *fixedWriter) Write(p []byte) (int, error) {
+	n := copy(w.b[w.n:], p)
+	w.n += n
+	if n < len(p) {
+		return n, errWidth
+	}
+	return n, nil
+}
+
+// crc32Combine returns the CRC-32 of the concatenation of two inputs given
+// crc1, the CRC-32 of the first, and crc2 and len2, the CRC-32 and length of
+// the second. It is a port of zlib's crc32_combine.
+func crc32Combine(crc1, crc2 uint32, len2 int64) uint32 {
+	if len2 <= 0 {
+		return crc1 ^ crc2
+	}
+
+	// odd holds the operator for one zero bit.
+	var even, odd [32]uint32
+	odd[0] = crc32.IEEE
+	row := uint32(1)
+	for n := 1; n < 32; n++ {
+		odd[n] = row
+		row <<= 1
+	}
+
+	gf2MatrixSquare(&even, &odd) // two zero bits
+	gf2MatrixSquare(&odd, &even) // four zero bits
+
+	// Apply len2 zero bytes to crc1.
+	for {
+		gf2MatrixSquare(&even, &odd)
+		if len2&1 != 0 {
+			crc1 = gf2MatrixTimes(&even, crc1)
+		}
+		if len2 >>= 1; len2 == 0 {
+			break
+		}
+
+		gf2MatrixSquare(&odd, &even)
+		if len2&1 != 0 {
+			crc1 = gf2MatrixTimes(&odd, crc1)
+		}
+		if len2 >>= 1; len2 == 0 {
+			break
+		}
+	}
+
+	return crc1 ^ crc2
+}
+
+func gf2MatrixTimes(mat *[32]uint32, vec uint32) uint32 {
+	var sum uint32
+	for i := 0; vec != 0; i, vec = i+1, vec>>1 {
+		if vec&1 != 0 {
+			sum ^= mat[i]
+		}
+	}
+	return sum
+}
+
+func gf2MatrixSquare(square, mat *[32]uint32) {
+	for n := range square {
+		square[n] = gf2MatrixTimes(mat, mat[n])
+	}
+}
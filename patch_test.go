@@ -0,0 +1,84 @@
+package gziptemplate
+
+import (
+	"bytes"
+	"hash/crc32"
+	"strings"
+	"testing"
+)
+
+func TestFixedWidths(t *testing.T) {
+	template := strings.Repeat("static ", 100) + "id=[id] at [ts]&lt;[esc]" + strings.Repeat(" tail", 100)
+	tpl := New(template, "[", "]", BestCompression, WithFixedWidths(map[string]int{
+		"id":  8,
+		"ts":  10,
+		"esc": 4,
+	}), WithEscaping(map[string]Escaping{
+		"esc": EscapeHTML,
+	}))
+	if tpl.patch == nil {
+		t.Fatal("expected template to be patchable")
+	}
+
+	for _, id := range []string{"abcdef01", "23456789"} {
+		m := map[string]interface{}{
+			"id":  id,
+			"ts":  "1234567890",
+			"esc": "<",
+		}
+		s := decompressBytes(t, tpl.ExecuteBytes(m))
+		result := strings.Repeat("static ", 100) + "id=" + id + " at 1234567890&lt;&lt;" + strings.Repeat(" tail", 100)
+		if string(s) != result {
+			t.Fatalf("unexpected template value %q. Expected %q", s, result)
+		}
+
+		var buf bytes.Buffer
+		if err := tpl.Execute(&buf, m); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if s := decompressBytes(t, buf.Bytes()); string(s) != result {
+			t.Fatalf("unexpected template value %q. Expected %q", s, result)
+		}
+	}
+
+	for _, id := range []string{"short", "far too long"} {
+		err := tpl.Execute(new(bytes.Buffer), map[string]interface{}{
+			"id":  id,
+			"ts":  "1234567890",
+			"esc": "<",
+		})
+		if err == nil {
+			t.Fatalf("expected error for value %q", id)
+		}
+	}
+}
+
+func TestFixedWidthsPartial(t *testing.T) {
+	tpl := New("[foo][bar]", "[", "]", BestCompression, WithFixedWidths(map[string]int{
+		"foo": 3,
+	}))
+	if tpl.patch != nil {
+		t.Fatal("expected template not to be patchable")
+	}
+
+	s := decompressBytes(t, tpl.ExecuteBytes(map[string]interface{}{"foo": "111", "bar": "22"}))
+	if string(s) != "11122" {
+		t.Fatalf("unexpected template value %q. Expected %q", s, "11122")
+	}
+}
+
+func TestFixedWidthsInvalid(t *testing.T) {
+	if _, err := NewTemplate("[foo]", "[", "]", BestCompression, WithFixedWidths(map[string]int{
+		"foo": 1 << 16,
+	})); err == nil {
+		t.Fatal("expected error for invalid width")
+	}
+}
+
+func TestCRC32Combine(t *testing.T) {
+	a, b := []byte("hello, "), []byte(strings.Repeat("world", 1000))
+	crc := crc32Combine(crc32.ChecksumIEEE(a), crc32.ChecksumIEEE(b), int64(len(b)))
+	if expect := crc32.ChecksumIEEE(append(a, b...)); crc != expect {
+		t.Fatalf("unexpected CRC-32 %08x. Expected %08x", crc, expect)
+	}
+}
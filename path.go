@@ -0,0 +1,98 @@
+package gziptemplate
+
+import (
+	"reflect"
+	"strings"
+)
+
+// resolve looks up the value for tag with lookup. If there is none and tag
+// contains dots, it is treated as a path: the part before the first dot is
+// looked up and each following part names a member of the previous value.
+func resolve(lookup func(tag string) (interface{}, bool), tag string) (interface{}, bool) {
+	v, ok := lookup(tag)
+	if ok {
+		return v, true
+	}
+
+	i := strings.IndexByte(tag, '.')
+	if i < 0 {
+		return nil, false
+	}
+
+	if v, ok = lookup(tag[:i]); !ok {
+		return nil, false
+	}
+
+	for _, name := range strings.Split(tag[i+1:], ".") {
+		if v, ok = member(v, name); !ok {
+			return nil, false
+		}
+	}
+
+	return v, true
+}
+
+// member returns the member of v called name: a map key or an exported
+// struct field.
+func member(v interface{}, name string) (interface{}, bool) {
+	if m, ok := v.(map[string]interface{}); ok {
+		v, ok := m[name]
+		return v, ok
+	}
+
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Ptr || rv.Kind() == reflect.Interface {
+		if rv.IsNil() {
+			return nil, false
+		}
+
+		rv = rv.Elem()
+	}
+
+	switch rv.Kind() {
+	case reflect.Struct:
+		sf, ok := rv.Type().FieldByName(name)
+		if !ok || sf.PkgPath != "" {
+			return nil, false
+		}
+
+		fv, ok := fieldByIndex(rv, sf.Index)
+		if !ok {
+			return nil, false
+		}
+
+		return fv.Interface(), true
+	case reflect.Map:
+		kt := rv.Type().Key()
+		if kt.Kind() != reflect.String {
+			return nil, false
+		}
+
+		mv := rv.MapIndex(reflect.ValueOf(name).Convert(kt))
+		if !mv.IsValid() {
+			return nil, false
+		}
+
+		return mv.Interface(), true
+	default:
+		return nil, false
+	}
+}
+
+// fieldByIndex is like reflect.Value.FieldByIndex but reports false, rather
+// than panicking, when it meets a nil embedded pointer.
+func fieldByIndex(v reflect.Value, index []int) (reflect.Value, bool) {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				return reflect.Value{}, false
+			}
+
+			v = v.Elem()
+		}
+
+		v = v.Field(x)
+	}
+
+	return v, true
+}
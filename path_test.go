@@ -0,0 +1,59 @@
+package gziptemplate
+
+import "testing"
+
+type pathUser struct {
+	Name    string
+	Address *pathAddress
+	*pathMeta
+
+	secret string
+}
+
+type pathAddress struct {
+	City string
+}
+
+type pathMeta struct {
+	Role string
+}
+
+func TestDotNotation(t *testing.T) {
+	template := "[user.Name]|[user.Address.City]|[user.Role]|[user.secret]|[user.Missing]|[site.name]|[site.links.home]|[a.b]|[row.user.Name]"
+	tpl := New(template, "[", "]", BestCompression)
+
+	s := decompressBytes(t, tpl.ExecuteBytes(map[string]interface{}{
+		"user": &pathUser{
+			Name:    "bob",
+			Address: &pathAddress{City: "Sydney"},
+			secret:  "x",
+		},
+		"site": map[string]interface{}{
+			"name":  "Example",
+			"links": map[string]string{"home": "/"},
+		},
+		"a.b": "literal",
+		"a":   map[string]interface{}{"b": "nested"},
+		"row": map[string]interface{}{"user": pathUser{Name: "alice"}},
+	}))
+	result := "bob|Sydney||||Example|/|literal|alice"
+	if string(s) != result {
+		t.Fatalf("unexpected template value %q. Expected %q", s, result)
+	}
+}
+
+func TestDotNotationRange(t *testing.T) {
+	tpl := New("[#range rows][row.Name]:[site.name];[#end]", "[", "]", BestCompression)
+
+	s := decompressBytes(t, tpl.ExecuteBytes(map[string]interface{}{
+		"site": map[string]interface{}{"name": "Example"},
+		"rows": []map[string]interface{}{
+			{"row": pathUser{Name: "a"}},
+			{"row": &pathUser{Name: "b"}},
+		},
+	}))
+	result := "a:Example;b:Example;"
+	if string(s) != result {
+		t.Fatalf("unexpected template value %q. Expected %q", s, result)
+	}
+}
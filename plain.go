@@ -0,0 +1,126 @@
+package gziptemplate
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+
+	"go.tmthrgd.dev/gzipbuilder"
+)
+
+// ExecuteFuncDual calls f on each template tag (placeholder) occurrence and
+// writes both the gzipped result to gzw and the identity (uncompressed)
+// result to plainw.
+//
+// Both outputs are produced in a single pass so that f is called exactly
+// once per tag, which matters when populating caches for several content
+// encodings from TagFuncs with side effects.
+func (t *Template) ExecuteFuncDual(gzw, plainw io.Writer, f TagFunc) error {
+	t.stats.executed()
+
+	if len(t.tags) == 0 {
+		if err := t.checkOutput(t.template); err != nil {
+			return err
+		}
+
+		if _, err := gzw.Write(t.template); err != nil {
+			return err
+		}
+
+		plain, err := t.plainTexts()
+		if err != nil {
+			return err
+		}
+
+		_, err = plainw.Write(plain[0])
+		return err
+	}
+
+	gzw, ol := t.limitOutput(gzw)
+	gw := gzipbuilder.NewWriter(gzw, t.level)
+	s := stream{
+		add:   func(d *gzipbuilder.PrecompressedData) { gw.AddPrecompressedData(d) },
+		w:     io.MultiWriter(gw.UncompressedWriter(), plainw),
+		plain: plainw,
+	}.withLimit(ol)
+
+	if err := t.execute(s, f); err != nil {
+		return err
+	}
+
+	return gw.Close()
+}
+
+// ExecuteDual substitutes template tags (placeholders) with the
+// corresponding values from the map m and writes both the gzipped result to
+// gzw and the identity (uncompressed) result to plainw.
+//
+// See ExecuteFuncDual for details.
+func (t *Template) ExecuteDual(gzw, plainw io.Writer, m map[string]interface{}) error {
+	return t.ExecuteFuncDual(gzw, plainw, t.mapTagFunc(m))
+}
+
+// ExecuteFuncPlain calls f on each template tag (placeholder) occurrence and
+// writes the identity (uncompressed) result to w.
+//
+// This serves clients that don't accept gzip from the same parsed template.
+// The uncompressed static segments are reconstructed on first use and then
+// kept with the template.
+func (t *Template) ExecuteFuncPlain(w io.Writer, f TagFunc) error {
+	t.stats.executed()
+	return t.render(w, f)
+}
+
+// ExecutePlain substitutes template tags (placeholders) with the
+// corresponding values from the map m and writes the identity
+// (uncompressed) result to w.
+//
+// See ExecuteFuncPlain for details.
+func (t *Template) ExecutePlain(w io.Writer, m map[string]interface{}) error {
+	return t.ExecuteFuncPlain(w, t.mapTagFunc(m))
+}
+
+// plainTexts returns the uncompressed static segments of t. They are
+// reconstructed from the precompressed segments on first use so that
+// templates that never need them don't pay to keep them in memory.
+func (t *Template) plainTexts() ([][]byte, error) {
+	t.plainOnce.Do(func() {
+		plain := make([][]byte, len(t.texts))
+		for i := range t.texts {
+			text, err := t.segmentText(i)
+			if err != nil {
+				t.plainErr = err
+				return
+			}
+
+			plain[i] = text
+		}
+
+		t.plain = plain
+	})
+
+	return t.plain, t.plainErr
+}
+
+// segmentText reconstructs the uncompressed text of the i'th static segment.
+func (t *Template) segmentText(i int) ([]byte, error) {
+	var buf bytes.Buffer
+	gw := gzipbuilder.NewWriter(&buf, t.level)
+	gw.AddPrecompressedData(t.texts[i])
+	if err := gw.Close(); err != nil {
+		return nil, err
+	}
+
+	r, err := gzip.NewReader(&buf)
+	if err != nil {
+		return nil, err
+	}
+
+	text, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	return text, r.Close()
+}
@@ -0,0 +1,95 @@
+package gziptemplate
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+func TestExecuteFuncDual(t *testing.T) {
+	for _, template := range []string{
+		"foo[foo]bar[bar]baz",
+		"[foo]",
+		"foobar",
+		"",
+	} {
+		tpl := New(template, "[", "]", BestCompression)
+
+		var calls int
+		var gz, plain bytes.Buffer
+		if err := tpl.ExecuteFuncDual(&gz, &plain, func(w io.Writer, tag string) error {
+			calls++
+			_, err := io.WriteString(w, "<"+tag+">")
+			return err
+		}); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+
+		if calls != len(tpl.tags) {
+			t.Fatalf("unexpected number of TagFunc calls %d. Expected %d", calls, len(tpl.tags))
+		}
+
+		s := decompressBytes(t, gz.Bytes())
+		if string(s) != plain.String() {
+			t.Fatalf("unexpected identity output %q. Expected %q", plain.String(), s)
+		}
+	}
+}
+
+func TestExecuteDual(t *testing.T) {
+	tpl := New("foo[foo]bar[#range items]<[foo]>[#end]", "[", "]", BestCompression)
+
+	var calls int
+	m := map[string]interface{}{
+		"foo": TagFunc(func(w io.Writer, tag string) error {
+			calls++
+			_, err := io.WriteString(w, "111")
+			return err
+		}),
+		"items": []map[string]interface{}{{"foo": "a"}, {"foo": "b"}},
+	}
+
+	var gz, plain bytes.Buffer
+	if err := tpl.ExecuteDual(&gz, &plain, m); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if calls != 1 {
+		t.Fatalf("unexpected number of TagFunc calls %d. Expected 1", calls)
+	}
+
+	const expect = "foo111bar<a><b>"
+	if s := decompressBytes(t, gz.Bytes()); string(s) != expect {
+		t.Fatalf("unexpected template value %q. Expected %q", s, expect)
+	}
+	if plain.String() != expect {
+		t.Fatalf("unexpected identity output %q. Expected %q", plain.String(), expect)
+	}
+}
+
+func TestExecutePlain(t *testing.T) {
+	for _, template := range []string{
+		"foo[foo]bar[#if bar]<[bar]>[#end]baz",
+		"[#range items][foo][#end]",
+		"foobar",
+		"",
+	} {
+		tpl := New(template, "[", "]", BestCompression, WithSmallestValues())
+
+		m := map[string]interface{}{
+			"foo":   "111",
+			"bar":   New("[foo]!", "[", "]", BestSpeed),
+			"items": []map[string]interface{}{{"foo": "a"}, {}},
+		}
+
+		var plain bytes.Buffer
+		if err := tpl.ExecutePlain(&plain, m); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+
+		s := decompressBytes(t, tpl.ExecuteBytes(m))
+		if string(s) != plain.String() {
+			t.Fatalf("unexpected identity output %q. Expected %q", plain.String(), s)
+		}
+	}
+}
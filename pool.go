@@ -0,0 +1,80 @@
+package gziptemplate
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"sync"
+)
+
+// RenderPool limits the number of templates being executed concurrently.
+//
+// Compressing responses is CPU bound, so a traffic spike that executes
+// thousands of templates at once only adds latency to every one of them.
+// A RenderPool admits at most a fixed number of executions at a time; the
+// remainder wait in turn until their context is done.
+//
+// Each execution renders into a reusable buffer while it holds its slot and
+// writes the result to its destination only after releasing the slot, so
+// slow clients do not hold up other executions.
+//
+// A RenderPool is safe for concurrent use by multiple goroutines.
+type RenderPool struct {
+	sem  chan struct{}
+	bufs sync.Pool
+}
+
+// NewRenderPool returns a RenderPool that admits at most workers concurrent
+// executions.
+func NewRenderPool(workers int) *RenderPool {
+	if workers <= 0 {
+		panic("gziptemplate: RenderPool requires at least one worker")
+	}
+
+	return &RenderPool{
+		sem: make(chan struct{}, workers),
+	}
+}
+
+// ExecuteFunc waits for a free slot, or for ctx to be done, and then calls
+// t.ExecuteFunc with f, writing the result to w.
+//
+// If ctx is done before a slot becomes free, ctx.Err() is returned and
+// nothing is written to w.
+func (p *RenderPool) ExecuteFunc(ctx context.Context, w io.Writer, t *Template, f TagFunc) error {
+	select {
+	case p.sem <- struct{}{}:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	buf, _ := p.bufs.Get().(*bytes.Buffer)
+	if buf == nil {
+		buf = new(bytes.Buffer)
+	}
+	defer p.bufs.Put(buf)
+
+	buf.Reset()
+	if err := p.render(buf, t, f); err != nil {
+		return err
+	}
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// render executes t into buf and then releases the slot held by the caller,
+// even if t panics.
+func (p *RenderPool) render(buf *bytes.Buffer, t *Template, f TagFunc) error {
+	defer func() { <-p.sem }()
+
+	return t.ExecuteFunc(buf, f)
+}
+
+// Execute waits for a free slot, or for ctx to be done, and then calls
+// t.Execute with m, writing the result to w.
+//
+// See ExecuteFunc for details.
+func (p *RenderPool) Execute(ctx context.Context, w io.Writer, t *Template, m map[string]interface{}) error {
+	return p.ExecuteFunc(ctx, w, t, t.mapTagFunc(m))
+}
@@ -0,0 +1,112 @@
+package gziptemplate
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRenderPool(t *testing.T) {
+	const workers = 2
+
+	tpl := New("foo[foo]bar", "[", "]", BestCompression)
+	p := NewRenderPool(workers)
+
+	var running, maxRunning int32
+	f := func(w io.Writer, tag string) error {
+		n := atomic.AddInt32(&running, 1)
+		defer atomic.AddInt32(&running, -1)
+
+		for {
+			max := atomic.LoadInt32(&maxRunning)
+			if n <= max || atomic.CompareAndSwapInt32(&maxRunning, max, n) {
+				break
+			}
+		}
+
+		time.Sleep(time.Millisecond)
+		_, err := io.WriteString(w, "111")
+		return err
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			var buf bytes.Buffer
+			if err := p.ExecuteFunc(context.Background(), &buf, tpl, f); err != nil {
+				t.Errorf("unexpected error: %s", err)
+				return
+			}
+
+			if s := decompressBytes(t, buf.Bytes()); string(s) != "foo111bar" {
+				t.Errorf("unexpected template value %q. Expected %q", s, "foo111bar")
+			}
+		}()
+	}
+	wg.Wait()
+
+	if maxRunning > workers {
+		t.Fatalf("%d concurrent executions exceeded limit of %d", maxRunning, workers)
+	}
+}
+
+func TestRenderPoolContext(t *testing.T) {
+	tpl := New("foo[foo]bar", "[", "]", BestCompression)
+	p := NewRenderPool(1)
+
+	release := make(chan struct{})
+	go p.ExecuteFunc(context.Background(), new(bytes.Buffer), tpl, func(w io.Writer, tag string) error {
+		<-release
+		return nil
+	})
+	defer close(release)
+
+	for len(p.sem) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	var buf bytes.Buffer
+	if err := p.Execute(ctx, &buf, tpl, nil); err != context.DeadlineExceeded {
+		t.Fatalf("unexpected error %v. Expected %v", err, context.DeadlineExceeded)
+	}
+	if buf.Len() != 0 {
+		t.Fatal("unexpected output written")
+	}
+}
+
+func TestRenderPoolPanic(t *testing.T) {
+	p := NewRenderPool(1)
+	tpl := New("foo[bar]baz", "[", "]", BestCompression)
+
+	func() {
+		defer func() {
+			if r := recover(); r != "boom" {
+				t.Fatalf("expected panic %q, got %v", "boom", r)
+			}
+		}()
+
+		p.ExecuteFunc(context.Background(), ioutil.Discard, tpl, func(w io.Writer, tag string) error {
+			panic("boom")
+		})
+	}()
+
+	// The slot held by the panicking execution must have been released.
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	var buf bytes.Buffer
+	if err := p.Execute(ctx, &buf, tpl, map[string]interface{}{"bar": "111"}); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+}
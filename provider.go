@@ -0,0 +1,135 @@
+package gziptemplate
+
+import (
+	"io"
+	"os"
+	"sync"
+)
+
+// Provider supplies substitution values by tag name.
+type Provider interface {
+	// Value returns the value of tag and whether it exists. The value may
+	// be of any type accepted by Execute.
+	Value(tag string) (interface{}, bool)
+}
+
+// ExecuteProvider substitutes template tags (placeholders) with the values
+// returned by p and writes the result to the given writer w.
+//
+// Tags for which p has no value are substituted with an empty string.
+func (t *Template) ExecuteProvider(w io.Writer, p Provider) error {
+	return t.ExecuteFunc(w, t.lookupTagFunc(p.Value))
+}
+
+// ExecuteProviderBytes substitutes template tags (placeholders) with the
+// values returned by p and returns the result.
+//
+// Tags for which p has no value are substituted with an empty string.
+func (t *Template) ExecuteProviderBytes(p Provider) []byte {
+	return t.ExecuteFuncBytes(t.lookupTagFunc(p.Value))
+}
+
+// Map is a Provider backed by a map of substitution values.
+type Map map[string]interface{}
+
+// Value implements Provider.
+func (m Map) Value(tag string) (interface{}, bool) {
+	v, ok := m[tag]
+	return v, ok
+}
+
+// Layers returns a Provider that resolves each tag from the first of
+// providers with a value for it.
+//
+// Providers are typically ordered from the most to the least specific, for
+// instance request-scoped values, then tenant values, then global defaults,
+// so that per-request maps stay small while shared values live in
+// long-lived layers.
+func Layers(providers ...Provider) Provider {
+	return layers(providers)
+}
+
+type layers []Provider
+
+func (l layers) Value(tag string) (interface{}, bool) {
+	for _, p := range l {
+		if v, ok := p.Value(tag); ok {
+			return v, true
+		}
+	}
+	return nil, false
+}
+
+// ConfigProvider is a Provider that resolves tags from a configuration
+// source, such as the environment, restricted to an allowlist of keys.
+//
+// Values are looked up once and cached for the lifetime of the provider.
+// This makes it well suited to infrastructure templates, such as server
+// configs or manifests, that are rendered from the environment they are
+// deployed into.
+//
+// A ConfigProvider is safe for concurrent use by multiple goroutines.
+type ConfigProvider struct {
+	lookup func(key string) (string, bool)
+	allow  map[string]bool
+
+	mu    sync.RWMutex
+	cache map[string]configValue
+}
+
+type configValue struct {
+	value string
+	ok    bool
+}
+
+// NewConfigProvider returns a ConfigProvider that resolves the tags named in
+// allow by calling lookup. All other tags are treated as missing so that
+// templates cannot read arbitrary keys.
+func NewConfigProvider(lookup func(key string) (string, bool), allow ...string) *ConfigProvider {
+	p := &ConfigProvider{
+		lookup: lookup,
+		allow:  make(map[string]bool, len(allow)),
+		cache:  make(map[string]configValue, len(allow)),
+	}
+	for _, key := range allow {
+		p.allow[key] = true
+	}
+	return p
+}
+
+// NewEnvProvider returns a ConfigProvider that resolves the tags named in
+// allow from environment variables.
+func NewEnvProvider(allow ...string) *ConfigProvider {
+	return NewConfigProvider(os.LookupEnv, allow...)
+}
+
+// Value implements Provider.
+func (p *ConfigProvider) Value(tag string) (interface{}, bool) {
+	if !p.allow[tag] {
+		return nil, false
+	}
+
+	p.mu.RLock()
+	v, cached := p.cache[tag]
+	p.mu.RUnlock()
+
+	if !cached {
+		v.value, v.ok = p.lookup(tag)
+
+		p.mu.Lock()
+		p.cache[tag] = v
+		p.mu.Unlock()
+	}
+
+	if !v.ok {
+		return nil, false
+	}
+	return v.value, true
+}
+
+// Reset discards all cached values so that they are looked up again.
+func (p *ConfigProvider) Reset() {
+	p.mu.Lock()
+	p.cache = make(map[string]configValue, len(p.allow))
+	p.mu.Unlock()
+}
@@ -0,0 +1,72 @@
+package gziptemplate
+
+import (
+	"os"
+	"testing"
+)
+
+func TestConfigProvider(t *testing.T) {
+	config := map[string]string{
+		"host":   "example.com",
+		"port":   "8080",
+		"secret": "hunter2",
+	}
+
+	var lookups int
+	p := NewConfigProvider(func(key string) (string, bool) {
+		lookups++
+		v, ok := config[key]
+		return v, ok
+	}, "host", "port", "missing")
+
+	tpl := New("listen [host]:[port]; [secret][missing]", "[", "]", BestCompression)
+	result := "listen example.com:8080; "
+
+	for i := 0; i < 2; i++ {
+		s := decompressBytes(t, tpl.ExecuteProviderBytes(p))
+		if string(s) != result {
+			t.Fatalf("unexpected template value %q. Expected %q", s, result)
+		}
+	}
+
+	if lookups != 3 {
+		t.Fatalf("unexpected number of lookups %d. Expected %d", lookups, 3)
+	}
+
+	config["port"] = "443"
+	p.Reset()
+
+	s := decompressBytes(t, tpl.ExecuteProviderBytes(p))
+	result = "listen example.com:443; "
+	if string(s) != result {
+		t.Fatalf("unexpected template value %q. Expected %q", s, result)
+	}
+}
+
+func TestEnvProvider(t *testing.T) {
+	os.Setenv("GZIPTEMPLATE_TEST_HOST", "example.com")
+	defer os.Unsetenv("GZIPTEMPLATE_TEST_HOST")
+
+	p := NewEnvProvider("GZIPTEMPLATE_TEST_HOST")
+	tpl := New("server_name {{GZIPTEMPLATE_TEST_HOST}};{{PATH}}", "{{", "}}", BestCompression)
+
+	s := decompressBytes(t, tpl.ExecuteProviderBytes(p))
+	result := "server_name example.com;"
+	if string(s) != result {
+		t.Fatalf("unexpected template value %q. Expected %q", s, result)
+	}
+}
+
+func TestLayers(t *testing.T) {
+	global := Map{"site": "Example", "theme": "light", "lang": "en"}
+	tenant := Map{"site": "Tenant", "theme": "dark"}
+	request := Map{"user": "John", "theme": nil}
+
+	tpl := New("[site]/[theme]/[lang]/[user]/[missing]", "[", "]", BestCompression)
+
+	s := decompressBytes(t, tpl.ExecuteProviderBytes(Layers(request, tenant, global)))
+	result := "Tenant//en/John/"
+	if string(s) != result {
+		t.Fatalf("unexpected template value %q. Expected %q", s, result)
+	}
+}
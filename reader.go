@@ -0,0 +1,43 @@
+package gziptemplate
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+)
+
+// ExecuteFuncReader returns a reader that produces the output of
+// ExecuteFunc as it is read, without buffering the whole output. It suits
+// APIs that pull their input, such as multipart writers and upload clients.
+//
+// The template is executed on another goroutine, which calls f, and is
+// paused while the output isn't being read. The reader must be read until
+// io.EOF or an error, or be closed, to release it. Errors from f, and
+// panics while executing, are returned from Read.
+func (t *Template) ExecuteFuncReader(f TagFunc) io.ReadCloser {
+	if len(t.tags) == 0 {
+		t.stats.executed()
+		return ioutil.NopCloser(bytes.NewReader(t.template))
+	}
+
+	pr, pw := io.Pipe()
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				pw.CloseWithError(fmt.Errorf("gziptemplate: panic while executing template: %v", r))
+			}
+		}()
+
+		pw.CloseWithError(t.ExecuteFunc(pw, f))
+	}()
+	return pr
+}
+
+// ExecuteReader returns a reader that produces the output of Execute as it
+// is read.
+//
+// See ExecuteFuncReader for details.
+func (t *Template) ExecuteReader(m map[string]interface{}) io.ReadCloser {
+	return t.ExecuteFuncReader(t.mapTagFunc(m))
+}
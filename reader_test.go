@@ -0,0 +1,54 @@
+package gziptemplate
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+func TestExecuteReader(t *testing.T) {
+	tpl := New("foo[bar]baz", "[", "]", BestCompression)
+
+	b, err := ioutil.ReadAll(tpl.ExecuteReader(map[string]interface{}{"bar": strings.Repeat("x", 1<<16)}))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	result := "foo" + strings.Repeat("x", 1<<16) + "baz"
+	if s := decompressBytes(t, b); string(s) != result {
+		t.Fatalf("unexpected template value of length %d. Expected length %d", len(s), len(result))
+	}
+
+	b, err = ioutil.ReadAll(New("static", "[", "]", BestCompression).ExecuteReader(nil))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if s := decompressBytes(t, b); string(s) != "static" {
+		t.Fatalf("unexpected template value %q. Expected %q", s, "static")
+	}
+
+	errTag := errors.New("tag error")
+	_, err = ioutil.ReadAll(tpl.ExecuteFuncReader(func(w io.Writer, tag string) error {
+		return errTag
+	}))
+	if !errors.Is(err, errTag) {
+		t.Fatalf("unexpected error %v. Expected %v", err, errTag)
+	}
+
+	r := tpl.ExecuteReader(map[string]interface{}{"bar": "1"})
+	if err := r.Close(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+}
+
+func TestExecuteReaderPanic(t *testing.T) {
+	tpl := New("foo[bar]baz", "[", "]", BestCompression)
+
+	_, err := ioutil.ReadAll(tpl.ExecuteFuncReader(func(w io.Writer, tag string) error {
+		panic("boom")
+	}))
+	if err == nil || !strings.Contains(err.Error(), "boom") {
+		t.Fatalf("expected error reporting the panic, got %v", err)
+	}
+}
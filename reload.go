@@ -0,0 +1,163 @@
+package gziptemplate
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Reloader reparses the templates of a TemplateSet when their files change.
+// It is created with TemplateSet.AutoReload.
+type Reloader struct {
+	set      *TemplateSet
+	pattern  string
+	onError  func(error)
+	interval time.Duration
+
+	mu    sync.Mutex
+	files map[string]fileState
+
+	stop      chan struct{}
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// fileState is used to detect changes to a file.
+type fileState struct {
+	size    int64
+	modTime time.Time
+}
+
+// AutoReload parses the files matched by pattern into s, as with ParseGlob,
+// and then checks them for changes every interval until the Reloader is
+// closed.
+//
+// When any file has been changed, added or removed, all the files are parsed
+// again and the resulting templates replace those in s at once, while the
+// templates of removed files are removed from s. Executions
+// already under way continue with the templates they started with. Errors
+// from checking and parsing the files are passed to onError, if non-nil,
+// and leave s unchanged. An error is returned if interval is not positive.
+//
+// This allows template edits to be picked up without restarting a server.
+func (s *TemplateSet) AutoReload(pattern string, interval time.Duration, onError func(error)) (*Reloader, error) {
+	if interval <= 0 {
+		return nil, fmt.Errorf("gziptemplate: non-positive AutoReload interval %s", interval)
+	}
+
+	r := &Reloader{
+		set:      s,
+		pattern:  pattern,
+		onError:  onError,
+		interval: interval,
+
+		stop: make(chan struct{}),
+		done: make(chan struct{}),
+	}
+	if err := r.Reload(); err != nil {
+		return nil, err
+	}
+
+	go r.run()
+	return r, nil
+}
+
+func (r *Reloader) run() {
+	defer close(r.done)
+
+	t := time.NewTicker(r.interval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-t.C:
+			if err := r.Reload(); err != nil && r.onError != nil {
+				r.onError(err)
+			}
+		case <-r.stop:
+			return
+		}
+	}
+}
+
+// Reload checks the files for changes immediately, parsing them again if
+// any have changed.
+func (r *Reloader) Reload() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	filenames, err := filepath.Glob(r.pattern)
+	if err != nil {
+		return err
+	}
+	if len(filenames) == 0 {
+		return fmt.Errorf("gziptemplate: pattern matches no files: %#q", r.pattern)
+	}
+
+	files := make(map[string]fileState, len(filenames))
+	changed := len(filenames) != len(r.files)
+	for _, filename := range filenames {
+		fi, err := os.Stat(filename)
+		if err != nil {
+			return err
+		}
+
+		state := fileState{fi.Size(), fi.ModTime()}
+		files[filename] = state
+		changed = changed || r.files[filename] != state
+	}
+	if !changed {
+		return nil
+	}
+
+	// Parse into a separate set so that all the templates are replaced
+	// at once, and none are replaced if any fail to parse. The set holds
+	// the other templates of r.set for the files to include, but not the
+	// old versions of the files nor those of removed files.
+	names := make(map[string]bool, len(filenames))
+	for _, filename := range filenames {
+		names[filepath.Base(filename)] = true
+	}
+
+	removed := make(map[string]bool)
+	for filename := range r.files {
+		if name := filepath.Base(filename); !names[name] {
+			removed[name] = true
+		}
+	}
+
+	stage := NewTemplateSet(r.set.opts...)
+	r.set.mu.RLock()
+	for name, t := range r.set.templates {
+		if !names[name] && !removed[name] {
+			stage.templates[name] = t
+		}
+	}
+	r.set.mu.RUnlock()
+
+	if err := stage.ParseFiles(filenames...); err != nil {
+		return err
+	}
+
+	r.set.mu.Lock()
+	for name := range names {
+		r.set.templates[name] = stage.templates[name]
+	}
+	for name := range removed {
+		delete(r.set.templates, name)
+	}
+	r.set.mu.Unlock()
+
+	r.files = files
+	return nil
+}
+
+// Close stops checking the files for changes. It may be called more than
+// once.
+func (r *Reloader) Close() error {
+	r.closeOnce.Do(func() { close(r.stop) })
+	<-r.done
+	return nil
+}
@@ -0,0 +1,97 @@
+package gziptemplate
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestAutoReload(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gziptemplate")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	write := func(name, text string, mtime time.Time) {
+		t.Helper()
+
+		filename := filepath.Join(dir, name)
+		if err := ioutil.WriteFile(filename, []byte(text), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(filename, mtime, mtime); err != nil {
+			t.Fatal(err)
+		}
+	}
+	check := func(s *TemplateSet, result string) {
+		t.Helper()
+
+		b := s.Lookup("a.html").ExecuteBytes(map[string]interface{}{"foo": "111"})
+		if s := decompressBytes(t, b); string(s) != result {
+			t.Fatalf("unexpected template value %q. Expected %q", s, result)
+		}
+	}
+
+	mtime := time.Now().Add(-time.Hour)
+	// The includer sorts first, so that it must be parsed after the
+	// header it includes.
+	write("a.html", `{{#include "header.html"}}{{foo}}`, mtime)
+	write("header.html", "v1:", mtime)
+
+	s := NewTemplateSet()
+	r, err := s.AutoReload(filepath.Join(dir, "*.html"), time.Hour, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	defer r.Close()
+
+	check(s, "v1:111")
+
+	write("header.html", "v2:", mtime.Add(time.Minute))
+	if err := r.Reload(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	check(s, "v2:111")
+
+	write("header.html", "{{", mtime.Add(2*time.Minute))
+	if err := r.Reload(); err == nil {
+		t.Fatal("expected error for invalid template")
+	}
+	check(s, "v2:111")
+
+	write("header.html", "v3:", mtime.Add(3*time.Minute))
+	write("b.html", "b", mtime)
+	if err := r.Reload(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if s.Lookup("b.html") == nil {
+		t.Fatal("expected added file to be parsed")
+	}
+
+	if err := os.Remove(filepath.Join(dir, "b.html")); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Reload(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if s.Lookup("b.html") != nil {
+		t.Fatal("expected template of removed file to be removed")
+	}
+	check(s, "v3:111")
+
+	// Close may be called again by the deferred call.
+	if err := r.Close(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+}
+
+func TestAutoReloadInterval(t *testing.T) {
+	for _, interval := range []time.Duration{0, -time.Second} {
+		if _, err := NewTemplateSet().AutoReload("*.html", interval, nil); err == nil {
+			t.Errorf("%s: expected error", interval)
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package gziptemplate
+
+import (
+	"bytes"
+	"io"
+)
+
+// Rendered is the gzipped output of an execution. It implements io.Reader
+// and io.WriterTo, so it may be passed to io.Copy and the like.
+type Rendered struct {
+	b []byte
+	r bytes.Reader
+}
+
+// Read implements io.Reader.
+func (r *Rendered) Read(p []byte) (int, error) {
+	return r.r.Read(p)
+}
+
+// WriteTo implements io.WriterTo.
+func (r *Rendered) WriteTo(w io.Writer) (int64, error) {
+	return r.r.WriteTo(w)
+}
+
+// Len returns the number of bytes of output not yet read.
+func (r *Rendered) Len() int {
+	return r.r.Len()
+}
+
+// Bytes returns the whole output, regardless of how much has been read. It
+// must not be modified.
+func (r *Rendered) Bytes() []byte {
+	return r.b
+}
+
+// RenderFunc is like ExecuteFunc but returns the output as a *Rendered.
+//
+// For templates without tags, the Rendered shares the precompressed output
+// of the template rather than copying it.
+func (t *Template) RenderFunc(f TagFunc) (*Rendered, error) {
+	var b []byte
+	if len(t.tags) == 0 {
+		t.stats.executed()
+		b = t.template
+	} else {
+		var err error
+		if b, err = t.ExecuteFuncBytesErr(f); err != nil {
+			return nil, err
+		}
+	}
+
+	r := &Rendered{b: b}
+	r.r.Reset(b)
+	return r, nil
+}
+
+// Render is like Execute but returns the output as a *Rendered.
+//
+// See RenderFunc for details.
+func (t *Template) Render(m map[string]interface{}) (*Rendered, error) {
+	return t.RenderFunc(t.mapTagFunc(m))
+}
@@ -0,0 +1,53 @@
+package gziptemplate
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"testing"
+)
+
+func TestRender(t *testing.T) {
+	tpl := New("foo[bar]baz", "[", "]", BestCompression)
+
+	r, err := tpl.Render(map[string]interface{}{"bar": "111"})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if r.Len() != len(r.Bytes()) {
+		t.Fatalf("unexpected length %d. Expected %d", r.Len(), len(r.Bytes()))
+	}
+
+	var buf bytes.Buffer
+	n, err := io.Copy(&buf, r)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if n != int64(len(r.Bytes())) || r.Len() != 0 {
+		t.Fatalf("unexpected number of bytes copied %d, %d remaining", n, r.Len())
+	}
+	if s := decompressBytes(t, buf.Bytes()); string(s) != "foo111baz" {
+		t.Fatalf("unexpected template value %q. Expected %q", s, "foo111baz")
+	}
+
+	static := New("static", "[", "]", BestCompression)
+	r, err = static.Render(nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if &r.Bytes()[0] != &static.template[0] {
+		t.Fatal("expected output of template without tags to be shared")
+	}
+
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if s := decompressBytes(t, b); string(s) != "static" {
+		t.Fatalf("unexpected template value %q. Expected %q", s, "static")
+	}
+
+	if _, err := tpl.Render(map[string]interface{}{"bar": []int{123}}); err == nil {
+		t.Fatal("expected error for unsupported value type")
+	}
+}
@@ -0,0 +1,173 @@
+package gziptemplate
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// Renderer executes a template for each of a stream of records
+// concurrently, which suits bulk generation of feeds, sitemaps and the like.
+//
+// A Renderer is safe for concurrent use by multiple goroutines.
+type Renderer struct {
+	t       *Template
+	workers int
+	ordered bool
+}
+
+// Result is the output of executing a template for one record.
+type Result struct {
+	// Index is the position of the record in the stream, from zero.
+	Index int
+
+	// Data is the gzipped output, or nil if Err is set. Err also reports
+	// panics while executing.
+	Data []byte
+	Err  error
+}
+
+// NewRenderer returns a Renderer that executes t with up to workers records
+// at a time. If ordered is true, results are delivered in the order of their
+// records, otherwise as soon as each is ready.
+func NewRenderer(t *Template, workers int, ordered bool) *Renderer {
+	if workers <= 0 {
+		panic("gziptemplate: Renderer requires at least one worker")
+	}
+
+	return &Renderer{
+		t:       t,
+		workers: workers,
+		ordered: ordered,
+	}
+}
+
+// Render executes the template for each record received from records until
+// it is closed, delivering the results on the returned channel, which is
+// closed once all have been delivered.
+//
+// If ctx is done, records are no longer received and the channel is closed
+// early. The returned channel must be drained, or ctx cancelled, to release
+// the Renderer's goroutines. At most twice as many records as there are
+// workers are held at once.
+func (r *Renderer) Render(ctx context.Context, records <-chan map[string]interface{}) <-chan Result {
+	type job struct {
+		index int
+		m     map[string]interface{}
+	}
+
+	var (
+		out     = make(chan Result)
+		jobs    = make(chan job)
+		results = make(chan Result)
+		tokens  = make(chan struct{}, 2*r.workers)
+	)
+
+	go func() {
+		defer close(jobs)
+
+		for i := 0; ; i++ {
+			select {
+			case tokens <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+
+			var (
+				m  map[string]interface{}
+				ok bool
+			)
+			select {
+			case m, ok = <-records:
+				if !ok {
+					return
+				}
+			case <-ctx.Done():
+				return
+			}
+
+			select {
+			case jobs <- job{i, m}:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	var wg sync.WaitGroup
+	wg.Add(r.workers)
+	for i := 0; i < r.workers; i++ {
+		go func() {
+			defer wg.Done()
+
+			for j := range jobs {
+				b, err := r.render(j.m)
+
+				select {
+				case results <- Result{Index: j.index, Data: b, Err: err}:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	go func() {
+		defer close(out)
+
+		done := false
+		send := func(res Result) {
+			if done {
+				return
+			}
+
+			select {
+			case out <- res:
+				<-tokens
+			case <-ctx.Done():
+				done = true
+			}
+		}
+
+		var (
+			next    int
+			pending = make(map[int]Result)
+		)
+		for res := range results {
+			if !r.ordered {
+				send(res)
+				continue
+			}
+
+			pending[res.Index] = res
+			for {
+				res, ok := pending[next]
+				if !ok {
+					break
+				}
+
+				delete(pending, next)
+				send(res)
+				next++
+			}
+		}
+	}()
+
+	return out
+}
+
+// render executes the template for m, returning any panic as an error.
+func (r *Renderer) render(m map[string]interface{}) (b []byte, err error) {
+	defer func() {
+		if rec := recover(); rec != nil {
+			b, err = nil, fmt.Errorf("gziptemplate: panic while executing template: %v", rec)
+		}
+	}()
+
+	return r.t.ExecuteBytesErr(m)
+}
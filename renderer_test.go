@@ -0,0 +1,113 @@
+package gziptemplate
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestRenderer(t *testing.T) {
+	tpl := New("<url>[loc]</url>", "[", "]", BestCompression)
+
+	const n = 50
+	records := func() <-chan map[string]interface{} {
+		ch := make(chan map[string]interface{})
+		go func() {
+			defer close(ch)
+			for i := 0; i < n; i++ {
+				i := i
+				ch <- map[string]interface{}{
+					"loc": TagFunc(func(w io.Writer, tag string) error {
+						// Make later records finish first.
+						time.Sleep(time.Duration(n-i) * 10 * time.Microsecond)
+						_, err := io.WriteString(w, strconv.Itoa(i))
+						return err
+					}),
+				}
+			}
+		}()
+		return ch
+	}
+
+	for _, ordered := range []bool{true, false} {
+		r := NewRenderer(tpl, 4, ordered)
+
+		seen := make(map[int]bool)
+		next := 0
+		for res := range r.Render(context.Background(), records()) {
+			if res.Err != nil {
+				t.Fatalf("unexpected error: %s", res.Err)
+			}
+			if ordered && res.Index != next {
+				t.Fatalf("unexpected result index %d. Expected %d", res.Index, next)
+			}
+			next++
+
+			result := fmt.Sprintf("<url>%d</url>", res.Index)
+			if s := decompressBytes(t, res.Data); string(s) != result {
+				t.Fatalf("unexpected template value %q. Expected %q", s, result)
+			}
+			seen[res.Index] = true
+		}
+
+		if len(seen) != n {
+			t.Fatalf("unexpected number of results %d. Expected %d", len(seen), n)
+		}
+	}
+}
+
+func TestRendererCancel(t *testing.T) {
+	tpl := New("[foo]", "[", "]", BestCompression)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	records := make(chan map[string]interface{})
+	go func() {
+		for {
+			select {
+			case records <- map[string]interface{}{"foo": []int{123}}:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	out := NewRenderer(tpl, 2, true).Render(ctx, records)
+
+	res := <-out
+	if res.Err == nil {
+		t.Fatal("expected error for unsupported value type")
+	}
+
+	cancel()
+	for range out {
+	}
+}
+
+func TestRendererPanic(t *testing.T) {
+	tpl := New("<url>[loc]</url>", "[", "]", BestCompression)
+
+	records := make(chan map[string]interface{}, 2)
+	records <- map[string]interface{}{"loc": TagFunc(func(w io.Writer, tag string) error {
+		panic("boom")
+	})}
+	records <- map[string]interface{}{"loc": "x"}
+	close(records)
+
+	var results []Result
+	for res := range NewRenderer(tpl, 1, true).Render(context.Background(), records) {
+		results = append(results, res)
+	}
+
+	if len(results) != 2 {
+		t.Fatalf("expected 2 results, got %d", len(results))
+	}
+	if results[0].Err == nil || results[0].Data != nil {
+		t.Fatalf("expected error for panicking record, got %v", results[0].Err)
+	}
+	if results[1].Err != nil {
+		t.Fatalf("unexpected error: %s", results[1].Err)
+	}
+}
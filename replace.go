@@ -0,0 +1,53 @@
+package gziptemplate
+
+import (
+	"fmt"
+
+	"go.tmthrgd.dev/gzipbuilder"
+)
+
+// ReplaceStatic returns a copy of t with the i'th static segment replaced by
+// text. Static segments are numbered from zero: segment i precedes the i'th
+// tag and the last segment follows the final tag.
+//
+// Only the replaced segment is compressed, all others are shared with t,
+// so small edits to a large template, such as changing a banner message,
+// are cheap.
+func (t *Template) ReplaceStatic(i int, text []byte) (*Template, error) {
+	if i < 0 || i >= len(t.texts) {
+		return nil, fmt.Errorf("gziptemplate: static segment %d out of range [0, %d)", i, len(t.texts))
+	}
+
+	w := gzipbuilder.NewPrecompressedWriter(t.level)
+	w.Write(text)
+	d, err := w.Data()
+	if err != nil {
+		return nil, err
+	}
+
+	nt := t.cloneSettings(nil)
+	nt.tags = t.tags
+	nt.texts = append([]*gzipbuilder.PrecompressedData(nil), t.texts...)
+	nt.textLens = append([]int64(nil), t.textLens...)
+	nt.texts[i] = d
+	nt.textLens[i] = int64(len(text))
+
+	if t.textPos != nil {
+		nt.textPos = append([]int64(nil), t.textPos...)
+		nt.textPos[i] = -1
+	}
+
+	if len(nt.tags) == 0 {
+		if nt.template, err = gzipSegment(nt.level, d); err != nil {
+			return nil, err
+		}
+	}
+
+	if t.patch != nil {
+		if nt.patch, err = newPatchImage(nt, t.patch.tagWidths(t)); err != nil {
+			return nil, err
+		}
+	}
+
+	return nt, nil
+}
@@ -0,0 +1,100 @@
+package gziptemplate
+
+import "testing"
+
+func TestReplaceStatic(t *testing.T) {
+	tpl := New("<p>Old banner</p>[foo]<footer>", "[", "]", BestCompression)
+
+	nt, err := tpl.ReplaceStatic(0, []byte("<p>New banner</p>"))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	m := map[string]interface{}{"foo": "111"}
+
+	s := decompressBytes(t, nt.ExecuteBytes(m))
+	result := "<p>New banner</p>111<footer>"
+	if string(s) != result {
+		t.Fatalf("unexpected template value %q. Expected %q", s, result)
+	}
+
+	s = decompressBytes(t, tpl.ExecuteBytes(m))
+	result = "<p>Old banner</p>111<footer>"
+	if string(s) != result {
+		t.Fatalf("unexpected template value %q. Expected %q", s, result)
+	}
+
+	if _, err := tpl.ReplaceStatic(2, nil); err == nil {
+		t.Fatal("expected error for out of range segment")
+	}
+}
+
+func TestReplaceStaticNoTags(t *testing.T) {
+	tpl := New("foo", "[", "]", BestCompression)
+
+	nt, err := tpl.ReplaceStatic(0, []byte("bar"))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	s := decompressBytes(t, nt.ExecuteBytes(nil))
+	if string(s) != "bar" {
+		t.Fatalf("unexpected template value %q. Expected %q", s, "bar")
+	}
+}
+
+func TestReplaceStaticFixedWidths(t *testing.T) {
+	tpl := New("id=[id];", "[", "]", BestCompression, WithFixedWidths(map[string]int{
+		"id": 3,
+	}))
+
+	nt, err := tpl.ReplaceStatic(1, []byte("!"))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if nt.patch == nil {
+		t.Fatal("expected template to be patchable")
+	}
+
+	s := decompressBytes(t, nt.ExecuteBytes(map[string]interface{}{"id": "123"}))
+	if string(s) != "id=123!" {
+		t.Fatalf("unexpected template value %q. Expected %q", s, "id=123!")
+	}
+}
+
+// settingsTemplate returns a template, named page, whose copies must keep
+// its separator, case folding and defaults to render as expected.
+func settingsTemplate(t *testing.T) *Template {
+	t.Helper()
+
+	tpl, err := NewTemplateSet().Parse("page", "<p>[items]</p>[title]", "[", "]", BestCompression, WithSeparator("; "), WithCaseInsensitive())
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	tpl.SetDefault("title", "Untitled")
+	return tpl
+}
+
+// checkSettings checks that tpl, a copy of a settingsTemplate, kept its
+// settings.
+func checkSettings(t *testing.T, tpl *Template, result string) {
+	t.Helper()
+
+	if tpl.name != "page" {
+		t.Errorf("unexpected name %q. Expected %q", tpl.name, "page")
+	}
+
+	s := decompressBytes(t, tpl.ExecuteBytes(map[string]interface{}{"ITEMS": []string{"a", "b"}}))
+	if string(s) != result {
+		t.Errorf("unexpected template value %q. Expected %q", s, result)
+	}
+}
+
+func TestReplaceStaticKeepsSettings(t *testing.T) {
+	nt, err := settingsTemplate(t).ReplaceStatic(0, []byte("<div>"))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	checkSettings(t, nt, "<div>a; b</p>Untitled")
+}
@@ -0,0 +1,177 @@
+package gziptemplate
+
+import (
+	"errors"
+	"io"
+)
+
+// tagOp is the kind of a tag.
+type tagOp uint8
+
+const (
+	// opValue is substituted with a value.
+	opValue tagOp = iota
+
+	// opIf, opElse and opEnd are the control tags of a conditional
+	// section resolved at execution time.
+	opIf
+	opElse
+	opEnd
+
+	// opRange begins a repeated section, which ends with opEnd.
+	opRange
+
+	// opHas begins a section written only if a value is given for the
+	// tag, used to implement defaults. It is followed by opElse and
+	// opEnd.
+	opHas
+
+	// opBlock begins a block that templates extending this one may
+	// override. It ends with opNop.
+	opBlock
+
+	// opNop separates static segments that could not be compressed
+	// together, such as those of included templates.
+	opNop
+)
+
+// branch evaluates the i'th tag of t, which must be a control tag, calling f
+// for the condition of opIf and the items of opRange. Repeated sections are
+// written to s. It returns the index of the tag after which execution
+// continues.
+func (t *Template) branch(s stream, tw io.Writer, i int, f TagFunc) (int, error) {
+	tag := &t.tags[i]
+	switch tag.op {
+	case opRange:
+		var rw rangeWriter
+		if err := f(&rw, tag.name); err != nil {
+			return 0, t.tagError(i, err)
+		}
+
+		for _, item := range rw.items {
+			if err := t.run(s, tw, i+1, tag.jump, t.itemTagFunc(item, f)); err != nil {
+				return 0, err
+			}
+		}
+		return tag.jump, nil
+	case opIf:
+		var cw condWriter
+		if err := f(&cw, tag.name); err != nil {
+			return 0, t.tagError(i, err)
+		}
+		if !cw.ok {
+			return tag.jump, nil
+		}
+		return i, nil
+	case opHas:
+		var hw hasWriter
+		if err := f(&hw, tag.name); err != nil {
+			return 0, t.tagError(i, err)
+		}
+		if !hw.ok {
+			return tag.jump, nil
+		}
+		return i, nil
+	case opElse:
+		return tag.jump, nil
+	default:
+		return i, nil
+	}
+}
+
+// condWriter determines the condition of a conditional section. The
+// condition holds if anything non-empty is written to it or if the value is
+// the boolean true.
+type condWriter struct {
+	ok bool
+}
+
+func (cw *condWriter) Write(p []byte) (int, error) {
+	if len(p) != 0 {
+		cw.ok = true
+	}
+	return len(p), nil
+}
+
+// holdsCondition reports whether w is a condWriter, marking its condition as
+// holding if so. Values that are called or consumed when written are not
+// written to a condWriter, as they would then be written again, or not at
+// all, with the section.
+func holdsCondition(w io.Writer) bool {
+	cw, ok := w.(*condWriter)
+	if ok {
+		cw.ok = true
+	}
+	return ok
+}
+
+// rangeWriter receives the items of a repeated section.
+type rangeWriter struct {
+	items []map[string]interface{}
+}
+
+func (rw *rangeWriter) Write(p []byte) (int, error) {
+	return 0, errRangeWrite
+}
+
+// errRangeWrite is returned when a value other than a slice of maps is given
+// for a repeated section.
+var errRangeWrite = errors.New("gziptemplate: #range requires a []map[string]interface{} value")
+
+// itemTagFunc returns a TagFunc that substitutes values from the item of a
+// repeated section, falling back to f for values the item lacks.
+func (t *Template) itemTagFunc(item map[string]interface{}, f TagFunc) TagFunc {
+	lookup := t.mapLookup(item)
+
+	var lazy lazyValues
+	var itemf, g TagFunc
+	itemf = func(w io.Writer, tag string) error {
+		v, _ := resolve(lookup, tag)
+		if nt, ok := v.(*Template); ok && !isHasWriter(w) {
+			return nt.writeTo(w, g)
+		}
+		if !isHasWriter(w) {
+			v = lazy.eval(tag, v)
+		}
+
+		return writeValue(w, tag, t.templateValue(tag, v))
+	}
+
+	g = func(w io.Writer, tag string) error {
+		if _, ok := resolve(lookup, tag); ok {
+			return itemf(w, tag)
+		}
+
+		return f(w, tag)
+	}
+	return g
+}
+
+// hasWriter determines whether a value is given for a tag. Values are not
+// written to it, but a TagFunc that writes anything is taken to have given
+// a value.
+type hasWriter struct {
+	ok bool
+}
+
+func (hw *hasWriter) Write(p []byte) (int, error) {
+	if len(p) != 0 {
+		hw.ok = true
+	}
+	return len(p), nil
+}
+
+func isHasWriter(w io.Writer) bool {
+	_, ok := w.(*hasWriter)
+	return ok
+}
+
+// isControlWriter reports whether w resolves a control tag, rather than
+// receiving a value to be written out.
+func isControlWriter(w io.Writer) bool {
+	switch w.(type) {
+	case *hasWriter, *condWriter, *rangeWriter:
+		return true
+	}
+	return false
+}
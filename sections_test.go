@@ -0,0 +1,153 @@
+package gziptemplate
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+func TestConditionalSections(t *testing.T) {
+	template := "a[#if foo]b[bar][#if baz]c[#else]d[#end]e[#else]f[bar][#end]g[#if foo]h[#end]"
+	tpl := New(template, "[", "]", BestCompression)
+
+	for _, test := range []struct {
+		m      map[string]interface{}
+		result string
+	}{
+		{map[string]interface{}{"foo": true, "baz": "x", "bar": "1"}, "ab1cegh"},
+		{map[string]interface{}{"foo": "x", "baz": false, "bar": "1"}, "ab1degh"},
+		{map[string]interface{}{"foo": false, "baz": true, "bar": "1"}, "af1g"},
+		{map[string]interface{}{"foo": "", "bar": "1"}, "af1g"},
+		{map[string]interface{}{"bar": "1"}, "af1g"},
+		{map[string]interface{}{
+			"foo": TagFunc(func(w io.Writer, tag string) error {
+				_, err := io.WriteString(w, "yes")
+				return err
+			}),
+			"bar": "1",
+		}, "ab1degh"},
+	} {
+		s := decompressBytes(t, tpl.ExecuteBytes(test.m))
+		if string(s) != test.result {
+			t.Fatalf("unexpected template value %q for %v. Expected %q", s, test.m, test.result)
+		}
+
+		var buf bytes.Buffer
+		if err := tpl.Execute(&buf, test.m); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if s := decompressBytes(t, buf.Bytes()); string(s) != test.result {
+			t.Fatalf("unexpected template value %q for %v. Expected %q", s, test.m, test.result)
+		}
+	}
+}
+
+func TestConditionalSectionsWithFlags(t *testing.T) {
+	template := "[#if flag]a[#if foo]b[#end][#else][#if foo]c[#end][#end]"
+	tpl := New(template, "[", "]", BestCompression, WithFlags(map[string]bool{"flag": false}))
+
+	s := decompressBytes(t, tpl.ExecuteBytes(map[string]interface{}{"foo": true}))
+	if string(s) != "c" {
+		t.Fatalf("unexpected template value %q. Expected %q", s, "c")
+	}
+}
+
+func TestConditionalSectionsNested(t *testing.T) {
+	item := New("<[#if bold]b[#else]i[#end]>", "[", "]", BestCompression)
+	page := New("[item]|[#if plain][item][#end]", "[", "]", BestCompression, WithEscaping(map[string]Escaping{
+		"item": EscapeHTML,
+	}))
+
+	s := decompressBytes(t, page.ExecuteBytes(map[string]interface{}{
+		"item":  item,
+		"bold":  true,
+		"plain": true,
+	}))
+	result := "&lt;b&gt;|&lt;b&gt;"
+	if string(s) != result {
+		t.Fatalf("unexpected template value %q. Expected %q", s, result)
+	}
+}
+
+func TestConditionalSectionsConsumedValues(t *testing.T) {
+	tpl := New("[#if r]<[r]>[#end][#if f]<[f]>[#end]", "[", "]", BestCompression)
+
+	var calls int
+	s := decompressBytes(t, tpl.ExecuteBytes(map[string]interface{}{
+		"r": strings.NewReader("hello"),
+		"f": TagFunc(func(w io.Writer, tag string) error {
+			calls++
+			_, err := io.WriteString(w, "world")
+			return err
+		}),
+	}))
+	result := "<hello><world>"
+	if string(s) != result {
+		t.Fatalf("unexpected template value %q. Expected %q", s, result)
+	}
+	if calls != 1 {
+		t.Fatalf("TagFunc called %d times. Expected once", calls)
+	}
+}
+
+func TestRangeSections(t *testing.T) {
+	template := "<table>[#range rows]<tr><td>[name]</td>[#if admin]<td>admin</td>[#end]<td>[site]</td></tr>[#end]</table>"
+	tpl := New(template, "[", "]", BestCompression)
+
+	m := map[string]interface{}{
+		"site": "example",
+		"rows": []map[string]interface{}{
+			{"name": "a", "admin": true},
+			{"name": "b"},
+			{"name": "c", "site": "other"},
+		},
+	}
+	s := decompressBytes(t, tpl.ExecuteBytes(m))
+	result := "<table>" +
+		"<tr><td>a</td><td>admin</td><td>example</td></tr>" +
+		"<tr><td>b</td><td>example</td></tr>" +
+		"<tr><td>c</td><td>other</td></tr>" +
+		"</table>"
+	if string(s) != result {
+		t.Fatalf("unexpected template value %q. Expected %q", s, result)
+	}
+
+	s = decompressBytes(t, tpl.ExecuteBytes(map[string]interface{}{}))
+	if string(s) != "<table></table>" {
+		t.Fatalf("unexpected template value %q. Expected %q", s, "<table></table>")
+	}
+
+	if err := tpl.Execute(ioutil.Discard, map[string]interface{}{"rows": "x"}); err == nil {
+		t.Fatal("expected error for invalid #range value")
+	}
+}
+
+func TestRangeSectionsNested(t *testing.T) {
+	tpl := New("[#range outer][x]:[#range inner][x][y],[#end];[#end]", "[", "]", BestCompression)
+
+	s := decompressBytes(t, tpl.ExecuteBytes(map[string]interface{}{
+		"y": "!",
+		"outer": []map[string]interface{}{
+			{"x": "a", "inner": []map[string]interface{}{{}, {"x": "b"}}},
+			{"x": "c"},
+		},
+	}))
+	result := "a:a!,b!,;c:;"
+	if string(s) != result {
+		t.Fatalf("unexpected template value %q. Expected %q", s, result)
+	}
+}
+
+func TestRangeSectionsErrors(t *testing.T) {
+	for _, template := range []string{
+		"[#range]x[#end]",
+		"[#range foo]x",
+		"[#range foo]x[#else]y[#end]",
+	} {
+		if _, err := NewTemplate(template, "[", "]", BestCompression); err == nil {
+			t.Fatalf("expected non-nil error for %q. got nil", template)
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package gziptemplate
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// TemplateSet is a collection of named templates that may be executed by
+// name or included in other templates with the include directive. See
+// WithTemplateSet.
+//
+// Templates must be added to a set before those including them are parsed.
+// A TemplateSet is safe for concurrent use, so templates may be replaced
+// while others are being executed.
+type TemplateSet struct {
+	mu        sync.RWMutex
+	templates map[string]*Template
+
+	opts []Option
+}
+
+// NewTemplateSet returns an empty TemplateSet. Templates loaded by ParseFiles,
+// ParseGlob and ParseFS are parsed with opts, which may include WithDelims
+// and WithLevel.
+func NewTemplateSet(opts ...Option) *TemplateSet {
+	return &TemplateSet{
+		templates: make(map[string]*Template),
+
+		opts: opts,
+	}
+}
+
+// Add adds t to the set under name, replacing any template of the same name.
+// Templates that already include name are unaffected, as are executions of
+// the replaced template already under way.
+func (s *TemplateSet) Add(name string, t *Template) {
+	s.mu.Lock()
+	s.templates[name] = t
+	s.mu.Unlock()
+}
+
+// Lookup returns the template added under name, or nil if there is none.
+func (s *TemplateSet) Lookup(name string) *Template {
+	if s == nil {
+		return nil
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.templates[name]
+}
+
+// ExecuteTemplate executes the template added under name as with Execute.
+func (s *TemplateSet) ExecuteTemplate(w io.Writer, name string, m map[string]interface{}) error {
+	t := s.Lookup(name)
+	if t == nil {
+		return fmt.Errorf("gziptemplate: no template %q in set", name)
+	}
+
+	err := t.Execute(w, m)
+
+	var ee *ExecError
+	if errors.As(err, &ee) && ee.Template == "" {
+		ee.Template = name
+	}
+
+	return err
+}
+
+// SetLevel replaces each template of s with a copy compressed again at level,
+// as with WithLevel, such as one recommended by RecommendLevel. If any
+// template fails to be compressed, s is left unchanged.
+//
+// Templates replaced with Add while SetLevel runs are kept, and templates
+// that include those of s keep the segments they included.
+func (s *TemplateSet) SetLevel(level int) error {
+	s.mu.RLock()
+	old := make(map[string]*Template, len(s.templates))
+	for name, t := range s.templates {
+		old[name] = t
+	}
+	s.mu.RUnlock()
+
+	recompressed := make(map[string]*Template, len(old))
+	for name, t := range old {
+		nt, err := t.WithLevel(level)
+		if err != nil {
+			return err
+		}
+
+		recompressed[name] = nt
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for name, nt := range recompressed {
+		if s.templates[name] == old[name] {
+			s.templates[name] = nt
+		}
+	}
+	return nil
+}
+
+// Parse parses the given template as with NewTemplate, allowing it to include
+// the templates of s, and adds it to s under name.
+func (s *TemplateSet) Parse(name, template, startTag, endTag string, level int, opts ...Option) (*Template, error) {
+	opts = append(opts[:len(opts):len(opts)], WithTemplateSet(s))
+
+	t, err := NewTemplate(template, startTag, endTag, level, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	t.name = name
+	s.Add(name, t)
+	return t, nil
+}
+
+// Bind returns a substitution value that renders the template added to s
+// under name with values from m, as with Template.Bind. The template is
+// looked up each time the value is rendered, so it reflects later calls to
+// Add.
+//
+// This allows components to be rendered with their own values, without
+// merging them into those of the enclosing template.
+func (s *TemplateSet) Bind(name string, m map[string]interface{}) TagFunc {
+	return func(w io.Writer, tag string) error {
+		t := s.Lookup(name)
+		if t == nil {
+			return fmt.Errorf("gziptemplate: no template %q in set", name)
+		}
+
+		return t.writeTo(w, t.mapTagFunc(m))
+	}
+}
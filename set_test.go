@@ -0,0 +1,155 @@
+package gziptemplate
+
+import (
+	"bytes"
+	"sync"
+	"testing"
+)
+
+func TestTemplateSet(t *testing.T) {
+	set := NewTemplateSet()
+	if _, err := set.Parse("header", "<h1>[title]</h1>", "[", "]", BestCompression); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if _, err := set.Parse("nav", `<nav>[#if user][user][#else]guest[#end]</nav>`, "[", "]", BestCompression); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if _, err := set.Parse("static", "<hr>", "[", "]", BestCompression); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	tpl, err := set.Parse("page", `[#include "header"][#include "nav"]<p>[body]</p>[#include "static"][#if user][#include "nav"][#end]`, "[", "]", BestCompression)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if set.Lookup("page") != tpl {
+		t.Fatal("expected template to be added to set")
+	}
+
+	m := map[string]interface{}{
+		"title": "Title",
+		"body":  "Body",
+		"user":  "bob",
+	}
+	result := "<h1>Title</h1><nav>bob</nav><p>Body</p><hr><nav>bob</nav>"
+
+	s := decompressBytes(t, tpl.ExecuteBytes(m))
+	if string(s) != result {
+		t.Fatalf("unexpected template value %q. Expected %q", s, result)
+	}
+
+	var buf bytes.Buffer
+	if _, err := tpl.ExecuteMapped(&buf, m); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if s := decompressBytes(t, buf.Bytes()); string(s) != result {
+		t.Fatalf("unexpected template value %q. Expected %q", s, result)
+	}
+
+	delete(m, "user")
+	s = decompressBytes(t, tpl.ExecuteBytes(m))
+	result = "<h1>Title</h1><nav>guest</nav><p>Body</p><hr>"
+	if string(s) != result {
+		t.Fatalf("unexpected template value %q. Expected %q", s, result)
+	}
+}
+
+func TestTemplateSetErrors(t *testing.T) {
+	set := NewTemplateSet()
+	for _, template := range []string{
+		`[#include "missing"]`,
+		`[#include missing]`,
+		`[#include]`,
+	} {
+		if _, err := set.Parse("page", template, "[", "]", BestCompression); err == nil {
+			t.Fatalf("expected non-nil error for %q. got nil", template)
+		}
+	}
+
+	if _, err := NewTemplate(`[#include "missing"]`, "[", "]", BestCompression); err == nil {
+		t.Fatal("expected non-nil error without template set")
+	}
+}
+
+func TestTemplateSetExecuteTemplate(t *testing.T) {
+	set := NewTemplateSet()
+	set.Add("a", New("a[foo]", "[", "]", BestCompression))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			var buf bytes.Buffer
+			if err := set.ExecuteTemplate(&buf, "a", map[string]interface{}{"foo": "1"}); err != nil {
+				t.Errorf("unexpected error: %s", err)
+				return
+			}
+			if s := decompressBytes(t, buf.Bytes()); string(s) != "a1" && string(s) != "b1" {
+				t.Errorf("unexpected template value %q", s)
+			}
+		}()
+	}
+
+	set.Add("a", New("b[foo]", "[", "]", BestCompression))
+	wg.Wait()
+
+	var buf bytes.Buffer
+	if err := set.ExecuteTemplate(&buf, "a", map[string]interface{}{"foo": "1"}); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if s := decompressBytes(t, buf.Bytes()); string(s) != "b1" {
+		t.Fatalf("unexpected template value %q. Expected %q", s, "b1")
+	}
+
+	if err := set.ExecuteTemplate(&buf, "missing", nil); err == nil {
+		t.Fatal("expected error for missing template")
+	}
+}
+
+func TestTemplateSetBind(t *testing.T) {
+	set := NewTemplateSet()
+	page := New("[card]", "[", "]", BestCompression)
+	m := map[string]interface{}{
+		"card": set.Bind("card", map[string]interface{}{"name": "a"}),
+	}
+
+	var buf bytes.Buffer
+	if err := page.Execute(&buf, m); err == nil {
+		t.Fatalf("expected error for missing template")
+	}
+
+	set.Add("card", New("<b>[name]</b>", "[", "]", BestCompression))
+
+	buf.Reset()
+	if err := page.Execute(&buf, m); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if s := decompressBytes(t, buf.Bytes()); string(s) != "<b>a</b>" {
+		t.Fatalf("unexpected template value %q. Expected %q", s, "<b>a</b>")
+	}
+}
+
+func TestTemplateSetSetLevel(t *testing.T) {
+	set := NewTemplateSet()
+	if _, err := set.Parse("page", "<p>[body]</p>", "[", "]", BestCompression); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if err := set.SetLevel(BestSpeed); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	tpl := set.Lookup("page")
+	if tpl.level != BestSpeed {
+		t.Fatalf("unexpected level %d. Expected %d", tpl.level, BestSpeed)
+	}
+	if s := decompressBytes(t, tpl.ExecuteBytes(map[string]interface{}{"body": "x"})); string(s) != "<p>x</p>" {
+		t.Fatalf("unexpected template value %q. Expected %q", s, "<p>x</p>")
+	}
+
+	if err := set.SetLevel(100); err == nil {
+		t.Fatal("expected error for invalid level")
+	}
+}
@@ -0,0 +1,75 @@
+package gziptemplate
+
+import (
+	"bytes"
+	"compress/flate"
+	"sync"
+
+	"go.tmthrgd.dev/gzipbuilder"
+)
+
+// newSmallestPool returns a pool of smallestWriters for the given level.
+func newSmallestPool(level int) *sync.Pool {
+	return &sync.Pool{
+		New: func() interface{} {
+			fw, err := flate.NewWriter(nil, level)
+			if err != nil {
+				// Templates are already compressed at level, so
+				// it must be valid.
+				panic(err)
+			}
+
+			return &smallestWriter{
+				fw:     fw,
+				stored: gzipbuilder.NewPrecompressedWriter(NoCompression),
+			}
+		},
+	}
+}
+
+// smallestWriter buffers a tag value so that it may be emitted in whichever
+// of compressed or stored blocks is smaller.
+type smallestWriter struct {
+	buf bytes.Buffer
+
+	fw     *flate.Writer
+	stored *gzipbuilder.PrecompressedWriter
+}
+
+func (sw *smallestWriter) Write(p []byte) (int, error) {
+	return sw.buf.Write(p)
+}
+
+// flush emits the buffered value to s and resets sw.
+func (sw *smallestWriter) flush(s stream) error {
+	defer sw.buf.Reset()
+
+	v := sw.buf.Bytes()
+	if len(v) == 0 {
+		return nil
+	}
+
+	var compressed countWriter
+	sw.fw.Reset(&compressed)
+	sw.fw.Write(v)
+	if err := sw.fw.Flush(); err != nil {
+		return err
+	}
+
+	// Each stored block holds up to 65535 bytes behind a 5 byte header.
+	stored := len(v) + 5*((len(v)+0xfffe)/0xffff)
+	if int64(compressed) < int64(stored) {
+		_, err := s.w.Write(v)
+		return err
+	}
+
+	sw.stored.Reset()
+	sw.stored.Write(v)
+
+	d, err := sw.stored.Data()
+	if err != nil {
+		return err
+	}
+
+	return s.splice(d, v)
+}
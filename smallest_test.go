@@ -0,0 +1,32 @@
+package gziptemplate
+
+import (
+	"bytes"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func TestSmallestValues(t *testing.T) {
+	random := make([]byte, 1<<17)
+	rand.New(rand.NewSource(1)).Read(random)
+	text := strings.Repeat("a", 1<<10)
+
+	tpl := New("foo[random]bar[text]baz[empty]", "[", "]", BestCompression, WithSmallestValues())
+
+	for i := 0; i < 2; i++ {
+		var buf bytes.Buffer
+		if err := tpl.Execute(&buf, map[string]interface{}{
+			"random": random,
+			"text":   text,
+		}); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+
+		s := decompressBytes(t, buf.Bytes())
+		result := "foo" + string(random) + "bar" + text + "baz"
+		if string(s) != result {
+			t.Fatal("unexpected template value")
+		}
+	}
+}
@@ -0,0 +1,153 @@
+package gziptemplate
+
+import (
+	"io"
+	"sort"
+
+	"go.tmthrgd.dev/gzipbuilder"
+)
+
+// Span maps a region of uncompressed output to the part of the template
+// that produced it.
+type Span struct {
+	// Start and End are the offsets of the region in the uncompressed
+	// output, End being exclusive.
+	Start, End int64
+
+	// IsTag reports whether the region holds the value of the tag named
+	// Tag, rather than static text.
+	IsTag bool
+	Tag   string
+
+	// Source is the offset in the template source of the tag's start
+	// delimiter or of the beginning of the static text. It is -1 for
+	// templates that were not parsed from a source, such as those
+	// created with a TemplateBuilder.
+	Source int64
+}
+
+// SourceMap maps the uncompressed output of an execution back to the
+// template source. Spans are ordered by, and cover all of, the output.
+type SourceMap []Span
+
+// Lookup returns the Span containing the given offset in the uncompressed
+// output.
+func (m SourceMap) Lookup(offset int64) (Span, bool) {
+	i := sort.Search(len(m), func(i int) bool {
+		return m[i].End > offset
+	})
+	if i == len(m) || m[i].Start > offset {
+		return Span{}, false
+	}
+	return m[i], true
+}
+
+// Tags returns the spans of m holding tag values, in order. These are the
+// dynamic regions of the output.
+func (m SourceMap) Tags() SourceMap {
+	var tags SourceMap
+	for _, span := range m {
+		if span.IsTag {
+			tags = append(tags, span)
+		}
+	}
+	return tags
+}
+
+// ExecuteFuncMapped is like ExecuteFunc but also returns a SourceMap
+// relating the uncompressed output to the template source.
+//
+// This helps identify the template region responsible when rendered output
+// fails validation further downstream.
+func (t *Template) ExecuteFuncMapped(w io.Writer, f TagFunc) (SourceMap, error) {
+	t.stats.executed()
+
+	rec := new(sourceRecorder)
+	if len(t.tags) == 0 {
+		if err := t.checkOutput(t.template); err != nil {
+			return nil, err
+		}
+
+		if _, err := w.Write(t.template); err != nil {
+			return nil, err
+		}
+
+		rec.static(t, 0)
+		return rec.m, nil
+	}
+
+	w, ol := t.limitOutput(w)
+	gw := gzipbuilder.NewWriter(w, t.level)
+	rec.w = gw.UncompressedWriter()
+	s := stream{
+		add: func(d *gzipbuilder.PrecompressedData) { gw.AddPrecompressedData(d) },
+		w:   rec,
+		rec: rec,
+	}.withLimit(ol)
+
+	if err := t.execute(s, f); err != nil {
+		return nil, err
+	}
+
+	if err := gw.Close(); err != nil {
+		return nil, err
+	}
+
+	return rec.m, nil
+}
+
+// ExecuteMapped is like Execute but also returns a SourceMap relating the
+// uncompressed output to the template source.
+//
+// See ExecuteFuncMapped for details.
+func (t *Template) ExecuteMapped(w io.Writer, m map[string]interface{}) (SourceMap, error) {
+	return t.ExecuteFuncMapped(w, t.mapTagFunc(m))
+}
+
+// sourceRecorder builds a SourceMap during execution. Tag values are written
+// through it so that their length is known.
+type sourceRecorder struct {
+	w   io.Writer
+	off int64
+	m   SourceMap
+}
+
+func (r *sourceRecorder) Write(p []byte) (int, error) {
+	n, err := r.w.Write(p)
+	r.off += int64(n)
+	return n, err
+}
+
+// static records the i'th static segment of t.
+func (r *sourceRecorder) static(t *Template, i int) {
+	pos := int64(-1)
+	if t.textPos != nil {
+		pos = t.textPos[i]
+	}
+
+	start := r.off
+	r.off += t.textLens[i]
+	r.add(Span{
+		Start:  start,
+		End:    r.off,
+		Source: pos,
+	})
+}
+
+// tag records the value of the i'th tag of t, which began at start.
+func (r *sourceRecorder) tag(t *Template, i int, start int64) {
+	r.add(Span{
+		Start:  start,
+		End:    r.off,
+		IsTag:  true,
+		Tag:    t.tags[i].name,
+		Source: t.tags[i].pos,
+	})
+}
+
+func (r *sourceRecorder) add(s Span) {
+	// Empty regions can't be looked up and only clutter the map.
+	if s.Start != s.End {
+		r.m = append(r.m, s)
+	}
+}
@@ -0,0 +1,68 @@
+package gziptemplate
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestExecuteMapped(t *testing.T) {
+	template := "foo[foo]bar\n[bar][baz]baz"
+	tpl := New(template, "[", "]", BestCompression, WithEscaping(map[string]Escaping{
+		"bar": EscapeHTML,
+	}))
+
+	var buf bytes.Buffer
+	m, err := tpl.ExecuteMapped(&buf, map[string]interface{}{
+		"foo": "111",
+		"bar": "<>",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	s := decompressBytes(t, buf.Bytes())
+	result := "foo111bar\n&lt;&gt;baz"
+	if string(s) != result {
+		t.Fatalf("unexpected template value %q. Expected %q", s, result)
+	}
+
+	expect := SourceMap{
+		{Start: 0, End: 3, Source: 0},
+		{Start: 3, End: 6, IsTag: true, Tag: "foo", Source: 3},
+		{Start: 6, End: 10, Source: 8},
+		{Start: 10, End: 18, IsTag: true, Tag: "bar", Source: 12},
+		{Start: 18, End: 21, Source: 22},
+	}
+	if !reflect.DeepEqual(m, expect) {
+		t.Fatalf("unexpected source map %+v. Expected %+v", m, expect)
+	}
+
+	if tags := m.Tags(); !reflect.DeepEqual(tags, SourceMap{expect[1], expect[3]}) {
+		t.Fatalf("unexpected tag spans %+v. Expected %+v", tags, SourceMap{expect[1], expect[3]})
+	}
+
+	span, ok := m.Lookup(12)
+	if !ok || span.Tag != "bar" {
+		t.Fatalf("unexpected span %+v for offset 12", span)
+	}
+
+	if _, ok := m.Lookup(21); ok {
+		t.Fatal("unexpected span for offset past end of output")
+	}
+}
+
+func TestExecuteMappedNoTags(t *testing.T) {
+	tpl := New("foobar", "[", "]", BestCompression)
+
+	var buf bytes.Buffer
+	m, err := tpl.ExecuteMapped(&buf, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	expect := SourceMap{{Start: 0, End: 6, Source: 0}}
+	if !reflect.DeepEqual(m, expect) {
+		t.Fatalf("unexpected source map %+v. Expected %+v", m, expect)
+	}
+}
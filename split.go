@@ -0,0 +1,232 @@
+package gziptemplate
+
+import (
+	"compress/gzip"
+	"errors"
+	"hash"
+	"hash/crc32"
+	"io"
+
+	"go.tmthrgd.dev/gzipbuilder"
+)
+
+// MinSplitSize is the smallest maximum part size accepted by ExecuteSplit.
+const MinSplitSize = 128
+
+// splitReserve is the number of bytes reserved in each part for the gzip
+// header and trailer and the blocks written by Flush and Close.
+const splitReserve = 10 + 8 + 2*8
+
+// Part describes a single gzip member written by ExecuteSplit.
+type Part struct {
+	// Index is the zero-based index of the part.
+	Index int
+
+	// CompressedLen is the length of the gzip member.
+	CompressedLen int64
+
+	// UncompressedLen is the length of the data it contains.
+	UncompressedLen int64
+
+	// CRC32 is the IEEE CRC-32 of the data it contains.
+	CRC32 uint32
+}
+
+// ExecuteFuncSplit calls f on each template tag (placeholder) occurrence and
+// writes the gzipped result as a series of parts, none of which is larger
+// than maxSize bytes.
+//
+// Each part is a complete gzip member written to the io.WriteCloser returned
+// by create, which is closed once the part is complete, or once execution
+// fails, in which case the part is incomplete. As gzip members may
+// be concatenated, the parts together form the full output. The returned
+// manifest describes each part in order.
+//
+// Splitting requires every part to be compressed independently, so the
+// precompressed static segments cannot be reused and the whole output is
+// compressed at the template's level as it is produced.
+func (t *Template) ExecuteFuncSplit(maxSize int64, create func(part int) (io.WriteCloser, error), f TagFunc) ([]Part, error) {
+	if maxSize < MinSplitSize {
+		return nil, errors.New("gziptemplate: maximum part size is too small")
+	}
+
+	t.stats.executed()
+
+	ol := newOutputLimit(t.limits)
+	sw := &splitWriter{
+		level:   t.level,
+		maxSize: maxSize,
+		create:  create,
+		limit:   ol,
+		crc:     crc32.NewIEEE(),
+	}
+	s := stream{
+		add:   func(*gzipbuilder.PrecompressedData) {},
+		w:     sw,
+		plain: sw,
+	}.withLimit(ol)
+
+	if err := t.execute(s, f); err != nil {
+		sw.abort()
+		return nil, err
+	}
+
+	if err := sw.close(); err != nil {
+		sw.abort()
+		return nil, err
+	}
+
+	return sw.parts, nil
+}
+
+// ExecuteSplit substitutes template tags (placeholders) with the
+// corresponding values from the map m and writes the gzipped result as a
+// series of parts, none of which is larger than maxSize bytes.
+//
+// See ExecuteFuncSplit for details.
+func (t *Template) ExecuteSplit(maxSize int64, create func(part int) (io.WriteCloser, error), m map[string]interface{}) ([]Part, error) {
+	return t.ExecuteFuncSplit(maxSize, create, t.mapTagFunc(m))
+}
+
+// splitWriter compresses everything written to it into a series of gzip
+// members of bounded size.
+type splitWriter struct {
+	level   int
+	maxSize int64
+	create  func(part int) (io.WriteCloser, error)
+
+	// limit, if non-nil, counts the parts against the output limits.
+	limit *outputLimit
+
+	parts []Part
+
+	w   io.WriteCloser
+	cw  countWriter
+	gw  *gzip.Writer
+	crc hash.Hash32
+
+	// uncompressed is the number of bytes written to the current part
+	// and pending the number not yet flushed by gw.
+	uncompressed int64
+	pending      int64
+}
+
+func (sw *splitWriter) Write(p []byte) (int, error) {
+	n := len(p)
+	for len(p) > 0 {
+		if sw.w == nil {
+			if err := sw.next(); err != nil {
+				return n - len(p), err
+			}
+		}
+
+		room := sw.room()
+		if room <= 0 && sw.pending > 0 {
+			// Flush to learn exactly how much space remains.
+			if err := sw.gw.Flush(); err != nil {
+				return n - len(p), err
+			}
+
+			sw.pending = 0
+			room = sw.room()
+		}
+		if room <= 0 {
+			if err := sw.close(); err != nil {
+				return n - len(p), err
+			}
+
+			continue
+		}
+
+		c := len(p)
+		if int64(c) > room {
+			c = int(room)
+		}
+
+		if _, err := sw.gw.Write(p[:c]); err != nil {
+			return n - len(p), err
+		}
+
+		sw.crc.Write(p[:c])
+		sw.uncompressed += int64(c)
+		sw.pending += int64(c)
+		p = p[c:]
+	}
+	return n, nil
+}
+
+// room returns how many more bytes may be written to the current part
+// without any risk of it exceeding maxSize.
+func (sw *splitWriter) room() int64 {
+	used := int64(sw.cw) + sw.pending + 5*(sw.pending/0xffff+1)
+	room := sw.maxSize - splitReserve - used
+
+	// Allow for the stored block headers deflate may need for the
+	// remaining space itself.
+	return room - 5*(room/0xffff+1)
+}
+
+// next starts a new part.
+func (sw *splitWriter) next() error {
+	w, err := sw.create(len(sw.parts))
+	if err != nil {
+		return err
+	}
+
+	sw.w = w
+	sw.cw = 0
+	sw.crc.Reset()
+	sw.uncompressed, sw.pending = 0, 0
+
+	out := io.MultiWriter(w, &sw.cw)
+	if sw.limit != nil {
+		out = &compressedLimitWriter{w: out, ol: sw.limit}
+	}
+
+	if sw.gw == nil {
+		sw.gw, err = gzip.NewWriterLevel(out, sw.level)
+		return err
+	}
+
+	sw.gw.Reset(out)
+	return nil
+}
+
+// close completes the current part, if any.
+func (sw *splitWriter) close() error {
+	if sw.w == nil {
+		if len(sw.parts) != 0 {
+			return nil
+		}
+
+		// Always write at least one part, even if empty.
+		if err := sw.next(); err != nil {
+			return err
+		}
+	}
+
+	err := sw.gw.Close()
+	if cerr := sw.w.Close(); err == nil {
+		err = cerr
+	}
+	sw.w = nil
+	if err != nil {
+		return err
+	}
+
+	sw.parts = append(sw.parts, Part{
+		Index:           len(sw.parts),
+		CompressedLen:   int64(sw.cw),
+		UncompressedLen: sw.uncompressed,
+		CRC32:           sw.crc.Sum32(),
+	})
+	return nil
+}
+
+// abort closes the current part, if any, after execution has failed.
+func (sw *splitWriter) abort() {
+	if sw.w != nil {
+		sw.w.Close()
+		sw.w = nil
+	}
+}
@@ -0,0 +1,118 @@
+package gziptemplate
+
+import (
+	"bytes"
+	"errors"
+	"hash/crc32"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+type nopWriteCloser struct{ io.Writer }
+
+func (nopWriteCloser) Close() error { return nil }
+
+func TestExecuteSplit(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	random := make([]byte, 100000)
+	rnd.Read(random)
+
+	tpl := New("foo[foo]bar[bar]baz", "[", "]", BestCompression)
+	m := map[string]interface{}{
+		"foo": random,
+		"bar": strings.Repeat("abc", 100000),
+	}
+	result := "foo" + string(random) + "bar" + strings.Repeat("abc", 100000) + "baz"
+
+	for _, maxSize := range []int64{MinSplitSize, 1000, 65536, 1 << 20} {
+		var bufs []*bytes.Buffer
+		parts, err := tpl.ExecuteSplit(maxSize, func(part int) (io.WriteCloser, error) {
+			if part != len(bufs) {
+				t.Fatalf("unexpected part index %d. Expected %d", part, len(bufs))
+			}
+
+			bufs = append(bufs, new(bytes.Buffer))
+			return nopWriteCloser{bufs[part]}, nil
+		}, m)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+
+		if len(parts) != len(bufs) {
+			t.Fatalf("unexpected number of parts %d. Expected %d", len(parts), len(bufs))
+		}
+
+		var all bytes.Buffer
+		for i, part := range parts {
+			b := bufs[i].Bytes()
+			if int64(len(b)) > maxSize {
+				t.Fatalf("part %d of %d bytes exceeds maximum size %d", i, len(b), maxSize)
+			}
+			if part.CompressedLen != int64(len(b)) {
+				t.Fatalf("unexpected compressed length %d. Expected %d", part.CompressedLen, len(b))
+			}
+
+			s := decompressBytes(t, b)
+			if part.UncompressedLen != int64(len(s)) || part.CRC32 != crc32.ChecksumIEEE(s) {
+				t.Fatalf("incorrect manifest for part %d", i)
+			}
+
+			all.Write(b)
+		}
+
+		if s := decompressBytes(t, all.Bytes()); string(s) != result {
+			t.Fatalf("unexpected template value with maximum size %d", maxSize)
+		}
+	}
+}
+
+func TestExecuteSplitEmpty(t *testing.T) {
+	tpl := New("", "[", "]", BestCompression)
+
+	var buf bytes.Buffer
+	parts, err := tpl.ExecuteSplit(MinSplitSize, func(part int) (io.WriteCloser, error) {
+		return nopWriteCloser{&buf}, nil
+	}, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if len(parts) != 1 || len(decompressBytes(t, buf.Bytes())) != 0 {
+		t.Fatalf("unexpected parts %+v", parts)
+	}
+}
+
+type closeCounter struct {
+	io.Writer
+	open *int
+}
+
+func (cc closeCounter) Close() error {
+	*cc.open--
+	return nil
+}
+
+func TestExecuteSplitClosesOnError(t *testing.T) {
+	tpl := New("foo[bar]baz", "[", "]", BestCompression)
+	errTag := errors.New("tag error")
+
+	var open int
+	_, err := tpl.ExecuteFuncSplit(MinSplitSize, func(int) (io.WriteCloser, error) {
+		open++
+		return closeCounter{ioutil.Discard, &open}, nil
+	}, func(w io.Writer, tag string) error {
+		if _, err := w.Write(bytes.Repeat([]byte("x"), 1000)); err != nil {
+			return err
+		}
+		return errTag
+	})
+	if !errors.Is(err, errTag) {
+		t.Fatalf("unexpected error %v. Expected %v", err, errTag)
+	}
+	if open != 0 {
+		t.Fatalf("%d parts were left open", open)
+	}
+}
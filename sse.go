@@ -0,0 +1,155 @@
+package gziptemplate
+
+import (
+	"context"
+	"io"
+	"net/http"
+
+	"go.tmthrgd.dev/gzipbuilder"
+)
+
+// ExecuteSSE streams server-sent events to w, rendering the template with
+// the values of each event received from events as the data of the event.
+// The stream is gzipped and flushed after each event. It returns once events
+// is closed, ctx is done or writing to w fails, as when the client goes
+// away; pass the request's context as ctx.
+//
+// Each line of the output becomes a "data:" line of the event. The static
+// segments of the template are framed once and precompressed as usual.
+//
+// The Content-Type, Content-Encoding and Cache-Control headers are set, if
+// not already, before the first event is written.
+func (t *Template) ExecuteSSE(ctx context.Context, w http.ResponseWriter, events <-chan map[string]interface{}) error {
+	ft, end, err := t.sseTemplate()
+	if err != nil {
+		return err
+	}
+
+	h := w.Header()
+	for _, kv := range [][2]string{
+		{"Content-Type", "text/event-stream"},
+		{"Content-Encoding", "gzip"},
+		{"Cache-Control", "no-cache"},
+	} {
+		if h.Get(kv[0]) == "" {
+			h.Set(kv[0], kv[1])
+		}
+	}
+
+	flush := flushFunc(w)
+	gw := gzipbuilder.NewWriter(w, t.level)
+	s := stream{
+		add: func(d *gzipbuilder.PrecompressedData) { gw.AddPrecompressedData(d) },
+		w:   gw.UncompressedWriter(),
+	}
+
+	for {
+		var (
+			m  map[string]interface{}
+			ok bool
+		)
+		select {
+		case m, ok = <-events:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		if !ok {
+			break
+		}
+
+		t.stats.executed()
+
+		if _, err := io.WriteString(s.w, "data: "); err != nil {
+			return err
+		}
+
+		f := t.mapTagFunc(m)
+		if err := ft.execute(s, func(w io.Writer, tag string) error {
+			if isControlWriter(w) {
+				return f(w, tag)
+			}
+
+			return f(&sseWriter{w: w}, tag)
+		}); err != nil {
+			return err
+		}
+
+		// Adding a precompressed segment writes out everything before
+		// it, so the event is complete once flushed.
+		s.add(end)
+		if flush != nil {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := gw.Close(); err != nil {
+		return err
+	}
+	if flush != nil {
+		return flush()
+	}
+	return nil
+}
+
+// sseTemplate returns t with its static segments framed as "data:" lines,
+// and the segment that ends each event. They are built on first use.
+func (t *Template) sseTemplate() (*Template, *gzipbuilder.PrecompressedData, error) {
+	t.sseOnce.Do(func() {
+		t.sse, t.sseErr = t.recompress(t.level, func(text []byte) []byte {
+			var cr bool
+			return sseFrame(nil, text, &cr)
+		})
+		if t.sseErr != nil {
+			return
+		}
+
+		pw := gzipbuilder.NewPrecompressedWriter(t.level)
+		pw.Write([]byte("\n\n"))
+		t.sseEnd, t.sseErr = pw.Data()
+	})
+
+	return t.sse, t.sseEnd, t.sseErr
+}
+
+// sseWriter frames the lines of tag values as "data:" lines.
+type sseWriter struct {
+	w   io.Writer
+	cr  bool
+	buf []byte
+}
+
+func (sw *sseWriter) Write(p []byte) (int, error) {
+	sw.buf = sseFrame(sw.buf[:0], p, &sw.cr)
+	if _, err := sw.w.Write(sw.buf); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// sseFrame appends p to dst with each line ending, be it CRLF, CR or LF,
+// replaced by the beginning of a new "data:" line. cr is set if p ends with
+// a CR that may yet be followed by LF.
+func sseFrame(dst, p []byte, cr *bool) []byte {
+	for _, c := range p {
+		if *cr {
+			*cr = false
+			if c == '\n' {
+				continue
+			}
+		}
+
+		switch c {
+		case '\r':
+			*cr = true
+			dst = append(dst, "\ndata: "...)
+		case '\n':
+			dst = append(dst, "\ndata: "...)
+		default:
+			dst = append(dst, c)
+		}
+	}
+
+	return dst
+}
@@ -0,0 +1,59 @@
+package gziptemplate
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestExecuteSSE(t *testing.T) {
+	tpl := New("<p>[msg]</p>\n<i>[#if more]more[#end]</i>", "[", "]", BestCompression)
+
+	events := make(chan map[string]interface{}, 2)
+	events <- map[string]interface{}{"msg": "hello"}
+	events <- map[string]interface{}{"msg": "two\r\nlines", "more": true}
+	close(events)
+
+	rec := httptest.NewRecorder()
+	if err := tpl.ExecuteSSE(context.Background(), rec, events); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if ct := rec.Header().Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("unexpected Content-Type %q", ct)
+	}
+	if ce := rec.Header().Get("Content-Encoding"); ce != "gzip" {
+		t.Fatalf("unexpected Content-Encoding %q", ce)
+	}
+	if !rec.Flushed {
+		t.Fatal("expected response to be flushed")
+	}
+
+	result := "data: <p>hello</p>\ndata: <i></i>\n\n" +
+		"data: <p>two\ndata: lines</p>\ndata: <i>more</i>\n\n"
+	if s := decompressBytes(t, rec.Body.Bytes()); string(s) != result {
+		t.Fatalf("unexpected template value %q. Expected %q", s, result)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := tpl.ExecuteSSE(ctx, httptest.NewRecorder(), make(chan map[string]interface{})); err != context.Canceled {
+		t.Fatalf("unexpected error %v. Expected %v", err, context.Canceled)
+	}
+}
+
+func TestExecuteSSECachesTemplate(t *testing.T) {
+	tpl := New("a\n[v]\nb", "[", "]", BestCompression)
+
+	ft1, end1, err := tpl.sseTemplate()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	ft2, end2, err := tpl.sseTemplate()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if ft1 != ft2 || end1 != end2 {
+		t.Fatal("expected the framed template to be reused")
+	}
+}
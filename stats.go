@@ -0,0 +1,65 @@
+package gziptemplate
+
+import "sync/atomic"
+
+// Stats reports how often a template and each of its tags have been
+// rendered since EnableStats was called.
+type Stats struct {
+	// Executions is the number of times the template was executed.
+	Executions uint64
+
+	// Tags maps each tag name to the number of times it was
+	// substituted. A tag that occurs more than once in the template is
+	// counted once per occurrence.
+	Tags map[string]uint64
+}
+
+type stats struct {
+	executions uint64
+	tags       []uint64
+}
+
+func (s *stats) executed() {
+	if s != nil {
+		atomic.AddUint64(&s.executions, 1)
+	}
+}
+
+func (s *stats) rendered(i int) {
+	if s != nil {
+		atomic.AddUint64(&s.tags[i], 1)
+	}
+}
+
+// EnableStats turns on accounting of how often the template and its tags
+// are rendered. It resets any previously gathered statistics.
+//
+// Accounting is off by default as it adds atomic operations to every
+// execution. EnableStats must not be called concurrently with the Execute*
+// methods.
+func (t *Template) EnableStats() {
+	t.stats = &stats{
+		tags: make([]uint64, len(t.tags)),
+	}
+}
+
+// Stats returns the statistics gathered since EnableStats was called. It
+// returns the zero Stats if accounting is not enabled.
+func (t *Template) Stats() Stats {
+	if t.stats == nil {
+		return Stats{}
+	}
+
+	s := Stats{
+		Executions: atomic.LoadUint64(&t.stats.executions),
+		Tags:       make(map[string]uint64, len(t.tags)),
+	}
+	for i, tag := range t.tags {
+		if tag.op != opValue {
+			continue
+		}
+
+		s.Tags[tag.name] += atomic.LoadUint64(&t.stats.tags[i])
+	}
+	return s
+}
@@ -0,0 +1,39 @@
+package gziptemplate
+
+import "testing"
+
+func TestStats(t *testing.T) {
+	tpl := New("[foo]bar[foo][baz]", "[", "]", BestCompression)
+
+	if s := tpl.Stats(); s.Executions != 0 || s.Tags != nil {
+		t.Fatalf("unexpected stats before EnableStats: %+v", s)
+	}
+
+	tpl.EnableStats()
+
+	m := map[string]interface{}{"foo": "111"}
+	tpl.ExecuteBytes(m)
+	tpl.ExecuteBytes(m)
+
+	s := tpl.Stats()
+	if s.Executions != 2 {
+		t.Fatalf("unexpected executions %d. Expected %d", s.Executions, 2)
+	}
+	if s.Tags["foo"] != 4 {
+		t.Fatalf("unexpected count for tag foo %d. Expected %d", s.Tags["foo"], 4)
+	}
+	if s.Tags["baz"] != 2 {
+		t.Fatalf("unexpected count for tag baz %d. Expected %d", s.Tags["baz"], 2)
+	}
+}
+
+func TestStatsNoTags(t *testing.T) {
+	tpl := New("foobar", "[", "]", BestCompression)
+	tpl.EnableStats()
+
+	tpl.ExecuteBytes(nil)
+
+	if s := tpl.Stats(); s.Executions != 1 || len(s.Tags) != 0 {
+		t.Fatalf("unexpected stats: %+v", s)
+	}
+}
@@ -0,0 +1,111 @@
+package gziptemplate
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+)
+
+// ExecuteStruct is like Execute but takes the substitution values from the
+// exported fields of the struct, or pointer to struct, v.
+//
+// A field is substituted for the tag of the same name, or for the name given
+// by a `gzt:"name"` struct tag. Fields tagged `gzt:"-"` are ignored. Fields of
+// embedded structs are promoted as in Go. Field values may be of any type
+// accepted by Execute, and dotted tags refer to members of fields as with
+// maps.
+//
+// The fields are found once for each type used with t, so there is no
+// reflection over the type on later executions.
+func (t *Template) ExecuteStruct(w io.Writer, v interface{}) error {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			return fmt.Errorf("gziptemplate: ExecuteStruct of nil %s", rv.Type())
+		}
+
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return fmt.Errorf("gziptemplate: ExecuteStruct of non-struct type %T", v)
+	}
+
+	fields := t.structFields(rv.Type())
+	return t.ExecuteFunc(w, t.lookupTagFunc(func(tag string) (interface{}, bool) {
+		index, ok := fields[tag]
+		if !ok {
+			return nil, false
+		}
+
+		fv, ok := fieldByIndex(rv, index)
+		if !ok {
+			return nil, false
+		}
+
+		return fv.Interface(), true
+	}))
+}
+
+// structFields returns the index of each field of the struct type typ by the
+// name it is substituted for.
+func (t *Template) structFields(typ reflect.Type) map[string][]int {
+	if fields, ok := t.structs.Load(typ); ok {
+		return fields.(map[string][]int)
+	}
+
+	fields := make(map[string][]int)
+	addStructFields(fields, typ, nil, make(map[reflect.Type]bool))
+
+	actual, _ := t.structs.LoadOrStore(typ, fields)
+	return actual.(map[string][]int)
+}
+
+// addStructFields adds the fields of typ, found through the field at index,
+// to fields. Fields already added, which are less deeply nested, take
+// precedence. Types in visited have been added already and are skipped, as
+// encoding/json does, so that types embedding themselves terminate.
+func addStructFields(fields map[string][]int, typ reflect.Type, index []int, visited map[reflect.Type]bool) {
+	if visited[typ] {
+		return
+	}
+	visited[typ] = true
+
+	var embedded []reflect.StructField
+	for i := 0; i < typ.NumField(); i++ {
+		sf := typ.Field(i)
+
+		name := sf.Tag.Get("gzt")
+		if name == "-" {
+			continue
+		}
+
+		ft := sf.Type
+		if ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+		if sf.Anonymous && name == "" && ft.Kind() == reflect.Struct {
+			embedded = append(embedded, sf)
+			continue
+		}
+
+		if sf.PkgPath != "" {
+			continue
+		}
+		if name == "" {
+			name = sf.Name
+		}
+
+		if _, dup := fields[name]; !dup {
+			fields[name] = append(index[:len(index):len(index)], i)
+		}
+	}
+
+	for _, sf := range embedded {
+		ft := sf.Type
+		if ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+
+		addStructFields(fields, ft, append(index[:len(index):len(index)], sf.Index...), visited)
+	}
+}
@@ -0,0 +1,81 @@
+package gziptemplate
+
+import (
+	"bytes"
+	"testing"
+)
+
+type structBase struct {
+	Site  string
+	Title string
+}
+
+type structPage struct {
+	*structBase
+	Title   string
+	User    string `gzt:"user_name"`
+	Admin   bool
+	Secret  string `gzt:"-"`
+	Profile struct{ Email string }
+	private string
+}
+
+func TestExecuteStruct(t *testing.T) {
+	tpl := New("[Site]/[Title] [user_name][#if Admin]!admin[#end] [Profile.Email] [Secret][private][User]", "[", "]", BestCompression)
+
+	v := structPage{
+		structBase: &structBase{Site: "example", Title: "base"},
+		Title:      "page",
+		User:       "bob",
+		Admin:      true,
+		Secret:     "s",
+		private:    "p",
+	}
+	v.Profile.Email = "bob@example.com"
+
+	for i := 0; i < 2; i++ {
+		var buf bytes.Buffer
+		if err := tpl.ExecuteStruct(&buf, &v); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+
+		result := "example/page bob!admin bob@example.com "
+		if s := decompressBytes(t, buf.Bytes()); string(s) != result {
+			t.Fatalf("unexpected template value %q. Expected %q", s, result)
+		}
+	}
+
+	v.structBase = nil
+	var buf bytes.Buffer
+	if err := tpl.ExecuteStruct(&buf, v); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	result := "/page bob!admin bob@example.com "
+	if s := decompressBytes(t, buf.Bytes()); string(s) != result {
+		t.Fatalf("unexpected template value %q. Expected %q", s, result)
+	}
+
+	if err := tpl.ExecuteStruct(&buf, map[string]interface{}{}); err == nil {
+		t.Fatal("expected error for non-struct value")
+	}
+	if err := tpl.ExecuteStruct(&buf, (*structPage)(nil)); err == nil {
+		t.Fatal("expected error for nil pointer")
+	}
+}
+
+type structSelf struct {
+	*structSelf
+	X string
+}
+
+func TestExecuteStructSelfEmbedding(t *testing.T) {
+	tpl := New("[X]", "[", "]", BestCompression)
+
+	var buf bytes.Buffer
+	if err := tpl.ExecuteStruct(&buf, structSelf{X: "x"}); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if s := decompressBytes(t, buf.Bytes()); string(s) != "x" {
+		t.Fatalf("unexpected template value %q. Expected %q", s, "x")
+	}
+}
@@ -10,10 +10,15 @@ package gziptemplate
 
 import (
 	"bytes"
-	"compress/gzip"
+	"encoding"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"go.tmthrgd.dev/gzipbuilder"
 )
@@ -31,10 +36,90 @@ const (
 // Template implements simple template engine, which can be used for fast
 // tags' (aka placeholders) substitution.
 type Template struct {
-	level    int
+	level int
+
+	// name is the name of the template within the TemplateSet that
+	// parsed it, if any.
+	name string
+
+	// startTag and endTag are the delimiters the template was parsed
+	// with, if any.
+	startTag, endTag string
+
+	// source holds the template source, unless discarded.
+	source string
+
+	// template holds the complete output of templates without tags.
 	template []byte
-	texts    []*gzipbuilder.PrecompressedData
-	tags     []string
+
+	// texts holds the static segments surrounding tags, there is always
+	// one more than there are tags.
+	texts []*gzipbuilder.PrecompressedData
+	tags  []tag
+
+	// textLens holds the uncompressed length of each static segment and
+	// textPos its offset in the template source, if known.
+	textLens []int64
+	textPos  []int64
+
+	middleware []Middleware
+	defaults   map[string]interface{}
+
+	// registered holds the precompressed values of tags, by tag and then
+	// value, see RegisterValues.
+	registered map[string]map[string]*Value
+
+	// missing, if non-nil, is called for tags without a value.
+	missing TagFunc
+
+	// foldCase reports whether tags match map keys without regard to
+	// case.
+	foldCase bool
+
+	// panics reports whether execution errors are raised as panics.
+	panics bool
+
+	// limits bounds the output of executions.
+	limits Limits
+
+	// observer, if non-nil, is notified of each substitution.
+	observer Observer
+
+	// separator, if non-nil, separates the elements of slice values in
+	// place of defaultSeparator.
+	separator *string
+
+	// patch, if non-nil, allows fixed-width values to be patched into a
+	// copy of the precompressed output.
+	patch *patchImage
+
+	// smallest, if non-nil, holds the writers used to pick the smaller
+	// encoding of each value.
+	smallest *sync.Pool
+
+	stats *stats
+
+	plainOnce sync.Once
+	plain     [][]byte
+	plainErr  error
+
+	// structs caches the fields of the struct types used with
+	// ExecuteStruct.
+	structs sync.Map
+
+	sizeOnce  sync.Once
+	sizes     []int64
+	sizeExtra int64
+	sizeErr   error
+
+	rawOnce sync.Once
+	raw     []rawSegment
+	rawErr  error
+
+	sseOnce sync.Once
+	sse     *Template
+	sseEnd  *gzipbuilder.PrecompressedData
+	sseErr  error
 }
 
 // New parses the given template using the given startTag and endTag
@@ -45,8 +130,8 @@ type Template struct {
 //
 // New panics if the given template cannot be parsed. Use NewTemplate instead
 // if template may contain errors.
-func New(template, startTag, endTag string, level int) *Template {
-	t, err := NewTemplate(template, startTag, endTag, level)
+func New(template, startTag, endTag string, level int, opts ...Option) *Template {
+	t, err := NewTemplate(template, startTag, endTag, level, opts...)
 	if err != nil {
 		panic(err)
 	}
@@ -56,85 +141,246 @@ func New(template, startTag, endTag string, level int) *Template {
 // NewTemplate parses the given template using the given startTag and endTag
 // as tag start and tag end.
 //
+// Whitespace before a tag is removed if the tag begins with "- ", and after
+// it if the tag ends with " -", as in [- name -]. This applies to all kinds
+// of tags, including directives.
+//
+// A literal start tag is written by doubling it, as in [[.
+//
+// A tag name containing dots, such as [user.name], that has no value of its
+// own refers to a member of the value for the part before the first dot.
+// Members are keys of maps with string keys and exported fields of structs,
+// through any pointers.
+//
+// A template may define blocks, written as [#block name]...[#end], that
+// templates extending it may override. A template extending another that was
+// added to the TemplateSet given by WithTemplateSet is written as:
+//
+//	[#extends "base"][#define name]...[#end]
+//
+// Everything outside of the [#define] sections is ignored. The result is the
+// base template with the blocks it defines replaced, compiled once, without
+// compressing the base template again.
+//
+// Text written between [#raw] and [#endraw] is copied through as static text
+// without being parsed, so it may contain the delimiters, as inline scripts
+// often do. The closing tag must be written exactly as [#endraw].
+//
+// Comments, written as [# comment #], are removed from the template and may
+// contain the delimiters.
+//
+// A template may contain conditional sections, written with "[" and "]" as
+// delimiters as:
+//
+//	[#if name]...[#else]...[#end]
+//
+// The [#else] branch is optional and sections may be nested. Unless name is
+// resolved at compile time by WithFlags, the first branch is written if the
+// value for name is true or renders to a non-empty string, and the second
+// otherwise. Values that are called or consumed when written, such as
+// TagFunc and io.Reader, are taken to be true without being written. The
+// static text of both branches is precompressed ahead of time.
+//
+// Tag values may be passed through filters, written as [name|filter]. Any
+// number of filters may be given and are applied in order, before any
+// escaping policy. See Filter.
+//
+// A tag may be given a default, written as [name|default text], that is
+// written when no value is given for name. It follows any filters and
+// begins at the first element that doesn't name a filter. The default text is
+// precompressed ahead of time like other static text.
+//
+// A template may also contain repeated sections, written as:
+//
+//	[#range name]...[#end]
+//
+// The value for name must be a []map[string]interface{}. The section is
+// written once for each map, with tags inside it substituted from that map
+// or, failing that, from the enclosing values.
+//
+// Syntax errors in the template are returned as a *ParseError giving their
+// position.
+//
 // The returned template can be executed by concurrently running goroutines
 // using Execute* methods.
-func NewTemplate(template, startTag, endTag string, level int) (*Template, error) {
-	if len(startTag) == 0 {
-		panic("gziptemplate: startTag cannot be empty")
-	}
-	if len(endTag) == 0 {
-		panic("gziptemplate: endTag cannot be empty")
-	}
+func NewTemplate(template, startTag, endTag string, level int, opts ...Option) (*Template, error) {
+	return parse(strings.NewReader(template), &template, startTag, endTag, level, opts)
+}
+
+// NewTemplateFromReader parses the template read from r using the given
+// startTag and endTag as tag start and tag end.
+//
+// The template is parsed and precompressed incrementally as it is read.
+// Aside from the compressed result, only a small read buffer and the name
+// of the tag currently being parsed are held in memory, so templates far
+// larger than available memory may be compiled.
+//
+// The returned template can be executed by concurrently running goroutines
+// using Execute* methods.
+func NewTemplateFromReader(r io.Reader, startTag, endTag string, level int, opts ...Option) (*Template, error) {
+	return parse(r, nil, startTag, endTag, level, opts)
+}
+
+// NewTemplateFromChunks parses a template assembled from the concatenation
+// of chunks, in order, using the given startTag and endTag as tag start and
+// tag end.
+//
+// The chunks are compiled as one logical template: static text and tags may
+// span chunk boundaries. This allows a template to be composed from, for
+// instance, a header file, a body string and a footer file without first
+// concatenating them in memory. Each chunk is read incrementally as with
+// NewTemplateFromReader.
+func NewTemplateFromChunks(chunks []io.Reader, startTag, endTag string, level int, opts ...Option) (*Template, error) {
+	return parse(io.MultiReader(chunks...), nil, startTag, endTag, level, opts)
+}
+
+// NewWithOptions parses the given template as with NewTemplate. The
+// delimiters default to "{{" and "}}" and the compression level to
+// DefaultCompression, and may be set with WithDelims and WithLevel.
+func NewWithOptions(template string, opts ...Option) (*Template, error) {
+	o := newOptions(opts)
+	return NewTemplate(template, o.startTag, o.endTag, o.level, opts...)
+}
+
+// NewTemplateBytes parses the given template using the given startTag and
+// endTag as tag start and tag end.
+//
+// Neither the template nor the delimiters need be valid UTF-8, so binary
+// protocols and mixed text and binary payloads may be templated. Tag names
+// are matched byte for byte against the keys passed to Execute*.
+//
+// The returned template can be executed by concurrently running goroutines
+// using Execute* methods.
+func NewTemplateBytes(template, startTag, endTag []byte, level int, opts ...Option) (*Template, error) {
+	return parse(bytes.NewReader(template), nil, string(startTag), string(endTag), level, opts)
+}
+
+// OnMissing sets f to be called in place of tags without a value, or
+// default, as with WithMissingHandler, replacing any handler given when the
+// template was compiled. A nil f removes the handler.
+//
+// This allows missing values to be logged, replaced by placeholders or taken
+// from a secondary source. OnMissing must not be called concurrently with
+// the Execute* methods.
+func (t *Template) OnMissing(f func(w io.Writer, tag string) error) {
+	t.missing = f
+}
 
-	t := &Template{
-		level: level,
+// Must is a helper that wraps a call to a function returning (*Template,
+// error) and panics if the error is non-nil. It is intended for use in
+// variable initializations such as
+//
+//	var t = gziptemplate.Must(gziptemplate.NewTemplate("[foo]", "[", "]", gziptemplate.BestCompression))
+func Must(t *Template, err error) *Template {
+	if err != nil {
+		panic(err)
 	}
+	return t
+}
 
-	tagsCount := strings.Count(template, startTag)
-	if tagsCount == 0 {
-		var buf bytes.Buffer
-		gw, err := gzip.NewWriterLevel(&buf, level)
-		if err != nil {
-			return nil, err
-		}
+// Source returns the source the template was parsed from. Templates parsed
+// with NewTemplate keep their source unless WithoutSource is given, while
+// those parsed from a reader, chunks or bytes keep it only if WithSource is
+// given. It returns the empty string if the source was not kept or if t was
+// not parsed, such as those built with a TemplateBuilder or returned by
+// Derive.
+func (t *Template) Source() string {
+	return t.source
+}
 
-		if _, err := gw.Write([]byte(template)); err != nil {
-			return nil, err
-		}
+// Delims returns the tag start and tag end delimiters the template was
+// parsed with, or empty strings if it was not parsed.
+func (t *Template) Delims() (startTag, endTag string) {
+	return t.startTag, t.endTag
+}
 
-		if err := gw.Close(); err != nil {
-			return nil, err
-		}
+// SetDefault sets the value substituted for tag when the map passed to
+// Execute, or the Provider passed to ExecuteProvider, has no value for it.
+// Setting a nil value removes the default.
+//
+// Defaults suit values common to every execution, such as a site name or a
+// support email address. SetDefault must not be called concurrently with the
+// Execute* methods.
+func (t *Template) SetDefault(tag string, value interface{}) {
+	if value == nil {
+		delete(t.defaults, tag)
+		return
+	}
 
-		t.template = buf.Bytes()
-		return t, nil
+	if t.defaults == nil {
+		t.defaults = make(map[string]interface{})
 	}
+	t.defaults[tag] = value
+}
 
-	t.texts = make([]*gzipbuilder.PrecompressedData, 0, tagsCount+1)
-	t.tags = make([]string, 0, tagsCount)
+// cloneSettings returns a template without segments or tags that has the
+// level, name, delimiters, execution options, defaults and registered values
+// of t. The tags of the defaults and registered values are passed through
+// rename, if non-nil.
+func (t *Template) cloneSettings(rename func(tag string) string) *Template {
+	nt := &Template{
+		level: t.level,
+		name:  t.name,
 
-	w := gzipbuilder.NewPrecompressedWriter(level)
+		startTag: t.startTag,
+		endTag:   t.endTag,
 
-	s := []byte(template)
-	st := template
+		middleware: t.middleware,
+		missing:    t.missing,
+		foldCase:   t.foldCase,
+		panics:     t.panics,
+		limits:     t.limits,
+		observer:   t.observer,
+		separator:  t.separator,
+		smallest:   t.smallest,
+	}
 
-	for {
-		if len(t.texts) > 0 {
-			w.Reset()
-		}
+	if rename == nil {
+		rename = func(tag string) string { return tag }
+	}
+	for tag, v := range t.defaults {
+		nt.SetDefault(rename(tag), v)
+	}
+	for tag, values := range t.registered {
+		nt.registerValues(rename(tag), values)
+	}
 
-		n := strings.Index(st, startTag)
-		ni := n
-		if n < 0 {
-			ni = len(st)
-		}
+	return nt
+}
 
-		w.Write(s[:ni])
-		d, err := w.Data()
-		if err != nil {
-			return nil, err
-		}
+// tag is a placeholder in a template.
+type tag struct {
+	name string
 
-		t.texts = append(t.texts, d)
-		if n < 0 {
-			break
-		}
+	// escape, if non-nil, is applied to everything written for the tag.
+	escape escapeFunc
 
-		s = s[n+len(startTag):]
-		st = st[n+len(startTag):]
+	// filterURL reports whether values begin a URL and are checked for
+	// unsafe schemes, see newURLFilter.
+	filterURL bool
 
-		n = strings.Index(st, endTag)
-		if n < 0 {
-			return nil, fmt.Errorf("gziptemplate: missing end tag=%q in template=%q starting from %q", endTag, template, st)
-		}
+	// src is the source text of the tag, delimiters included, if it was
+	// parsed. ExecuteStd writes it for tags without a value.
+	src string
+
+	// pos is the offset of the tag in the template source, or -1.
+	pos int64
 
-		t.tags = append(t.tags, st[:n])
+	// op is the kind of tag. For the control tags of conditional
+	// sections, jump is the index of the tag execution continues after
+	// when the branch is not taken.
+	op   tagOp
+	jump int
+}
 
-		s = s[n+len(endTag):]
-		st = st[n+len(endTag):]
+// escaper returns the escapeFunc to apply to everything written for one
+// occurrence of the tag.
+func (tg *tag) escaper() escapeFunc {
+	if tg.filterURL {
+		return chainEscape(newURLFilter(), tg.escape)
 	}
 
-	return t, nil
+	return tg.escape
 }
 
 // TagFunc can be used as a substitution value in the map passed to Execute*.
@@ -144,27 +390,242 @@ func NewTemplate(template, startTag, endTag string, level int) (*Template, error
 // running goroutines.
 type TagFunc func(w io.Writer, tag string) error
 
+// TagWriterFunc is like TagFunc but also returns the number of bytes written,
+// as functions modelled on io.Writer naturally do. It can be used as a
+// substitution value in the map passed to Execute*, the count being ignored.
+type TagWriterFunc func(w io.Writer, tag string) (int, error)
+
 // ExecuteFunc calls f on each template tag (placeholder) occurrence.
 func (t *Template) ExecuteFunc(w io.Writer, f TagFunc) error {
-	n := len(t.texts) - 1
-	if n == -1 {
+	t.stats.executed()
+
+	if len(t.tags) == 0 {
+		if err := t.checkOutput(t.template); err != nil {
+			return err
+		}
+
 		_, err := w.Write(t.template)
 		return err
 	}
 
+	if t.patch != nil {
+		b, err := t.patch.execute(t, f)
+		if err == nil {
+			err = t.checkOutput(b)
+		}
+		if err != nil {
+			return t.failed(err)
+		}
+
+		_, err = w.Write(b)
+		return err
+	}
+
+	w, ol := t.limitOutput(w)
 	gw := gzipbuilder.NewWriter(w, t.level)
-	uw := gw.UncompressedWriter()
+	s := stream{
+		add: func(d *gzipbuilder.PrecompressedData) { gw.AddPrecompressedData(d) },
+		w:   gw.UncompressedWriter(),
+	}.withLimit(ol)
 
-	for i := 0; i < n; i++ {
-		gw.AddPrecompressedData(t.texts[i])
+	if err := t.execute(s, f); err != nil {
+		return err
+	}
 
-		if err := f(uw, t.tags[i]); err != nil {
+	return gw.Close()
+}
+
+// stream is the destination of an execution. Precompressed static segments
+// are spliced in with add while tag values are written to w.
+type stream struct {
+	add func(*gzipbuilder.PrecompressedData)
+	w   io.Writer
+
+	// segment, if non-nil, is called for the i'th static segment of t in
+	// place of add, and values that would be spliced in are written to w
+	// instead.
+	segment func(t *Template, i int) error
+
+	// plain, if non-nil, also receives the uncompressed static segments.
+	// w must then write tag values to plain too.
+	plain io.Writer
+
+	// rec, if non-nil, records the source of the output. w must then
+	// write tag values to rec.
+	rec *sourceRecorder
+
+	// limit, if non-nil, counts the static segments against the output
+	// limits. w must then count tag values too.
+	limit *outputLimit
+
+	// obs, if non-nil, counts the static segments of nested templates
+	// for an Observer. w must then count tag values too.
+	obs *observation
+}
+
+// text writes the i'th static segment of t to s.
+func (s stream) text(t *Template, i int) error {
+	if s.limit != nil {
+		s.limit.uncompressed += t.textLens[i]
+		if err := s.limit.err(); err != nil {
 			return err
 		}
 	}
 
-	gw.AddPrecompressedData(t.texts[n])
-	return gw.Close()
+	s.observed(t.textLens[i])
+	if s.segment == nil {
+		s.add(t.texts[i])
+	} else if err := s.segment(t, i); err != nil {
+		return err
+	}
+	if s.rec != nil {
+		s.rec.static(t, i)
+	}
+	if s.plain == nil {
+		return nil
+	}
+
+	plain, err := t.plainTexts()
+	if err != nil {
+		return err
+	}
+
+	_, err = s.plain.Write(plain[i])
+	return err
+}
+
+// splice adds d, the precompressed form of the tag value v, to s. Bypassing
+// s.w, it keeps any plain copy, source map and counts in step.
+func (s stream) splice(d *gzipbuilder.PrecompressedData, v []byte) error {
+	if s.segment != nil {
+		_, err := s.w.Write(v)
+		return err
+	}
+
+	if s.limit != nil {
+		s.limit.uncompressed += int64(len(v))
+		if err := s.limit.err(); err != nil {
+			return err
+		}
+	}
+
+	s.add(d)
+
+	s.observed(int64(len(v)))
+	if s.rec != nil {
+		s.rec.off += int64(len(v))
+	}
+	if s.plain != nil {
+		_, err := s.plain.Write(v)
+		return err
+	}
+	return nil
+}
+
+// execute writes the template to s, calling f on each tag occurrence.
+func (t *Template) execute(s stream, f TagFunc) error {
+	// Entry points that know the gzipped output install the limits
+	// themselves, otherwise only the uncompressed output is counted.
+	if s.limit == nil {
+		s = s.withLimit(newOutputLimit(t.limits))
+	}
+
+	// Nested templates may be spliced into s, but not while recording a
+	// source map as their spans would overlap the enclosing tag's.
+	var tw io.Writer = s.w
+	if s.rec == nil {
+		tw = &spliceWriter{s}
+	}
+
+	return t.failed(t.run(s, tw, 0, len(t.texts)-1, f))
+}
+
+// failed returns err, unless the template was compiled with
+// WithPanicOnError in which case a non-nil err is raised as a panic.
+func (t *Template) failed(err error) error {
+	if err != nil && t.panics {
+		panic(fmt.Sprintf("gziptemplate: unexpected error from TagFunc: %s", err))
+	}
+
+	return err
+}
+
+// run writes the static segments i through n of t to s, calling f on each
+// tag occurrence in between. Tag values are written to tw.
+//
+// The middleware of t wraps f for values only: the conditions of sections
+// are resolved by f directly so that writers wrapped by middleware cannot
+// hide the kind of tag being resolved.
+func (t *Template) run(s stream, tw io.Writer, i, n int, f TagFunc) error {
+	vf := f
+	if len(t.middleware) != 0 {
+		vf = Chain(f, t.middleware...)
+	}
+
+	for ; ; i++ {
+		if err := s.text(t, i); err != nil {
+			return err
+		}
+		if i == n {
+			return nil
+		}
+
+		if t.tags[i].op != opValue {
+			var err error
+			if i, err = t.branch(s, tw, i, f); err != nil {
+				return err
+			}
+			continue
+		}
+
+		vs, w := s, tw
+
+		var ob *observation
+		var began time.Time
+		if t.observer != nil {
+			vs, w, ob = s.observe(tw)
+			began = time.Now()
+		}
+
+		var sw *smallestWriter
+		if t.smallest != nil {
+			sw = t.smallest.Get().(*smallestWriter)
+			w = sw
+		}
+
+		raw := w
+		if escape := t.tags[i].escaper(); escape != nil {
+			w = &escapeWriter{w: w, escape: escape}
+		}
+
+		var start int64
+		if s.rec != nil {
+			start = s.rec.off
+		}
+
+		t.stats.rendered(i)
+		err := vf(w, t.tags[i].name)
+		if err == errVerbatim {
+			err = t.writeSource(raw, i)
+		}
+
+		if err == nil && sw != nil {
+			err = sw.flush(vs)
+			t.smallest.Put(sw)
+		}
+
+		if ob != nil {
+			t.observer.Observe(t.tags[i].name, ob.n, time.Since(began), err)
+		}
+
+		if err != nil {
+			return t.tagError(i, err)
+		}
+
+		if s.rec != nil {
+			s.rec.tag(t, i, start)
+		}
+	}
 }
 
 // Execute substitutes template tags (placeholders) with the corresponding
@@ -174,35 +635,85 @@ func (t *Template) ExecuteFunc(w io.Writer, f TagFunc) error {
 //   * []byte - the fastest value type
 //   * string - convenient value type
 //   * TagFunc - flexible value type
+//   * TagWriterFunc - TagFunc that also returns the number of bytes written
+//   * *Value - precompressed value, see NewValue
+//   * Escaped - value escaped when written, see HTML and URLQuery
+//   * []string, [][]byte - elements separated by commas, see WithSeparator
+//   * Joined - elements separated by a given separator
+//   * func() []byte - called at most once per execution for each tag
+//   * func(tag string) ([]byte, error) - called for each tag occurrence
+//   * int, int64, uint64, float64 - formatted with strconv
+//   * bool - written as true or false, or the condition of an #if section
+//   * encoding.TextMarshaler - written as its text encoding
+//   * fmt.Stringer - written as returned by String
+//   * json.Marshaler - written as its JSON encoding
+//   * io.WriterTo, io.Reader - streamed until EOF, consuming the value
+//   * *Template - nested template sharing the same values, see Bind
 func (t *Template) Execute(w io.Writer, m map[string]interface{}) error {
-	return t.ExecuteFunc(w, func(w io.Writer, tag string) error {
-		return stdTagFunc(w, tag, m)
-	})
+	return t.ExecuteFunc(w, t.mapTagFunc(m))
 }
 
 // ExecuteFuncBytes calls f on each template tag (placeholder) occurrence
 // and substitutes it with the data written to TagFunc's w.
 //
-// Returns the resulting byte slice.
+// Returns the resulting byte slice. It panics if f returns an error or an
+// output limit is exceeded, see ExecuteFuncBytesErr.
 func (t *Template) ExecuteFuncBytes(f TagFunc) []byte {
-	n := len(t.texts) - 1
-	if n == -1 {
+	t.stats.executed()
+
+	if len(t.tags) == 0 {
+		if err := t.checkOutput(t.template); err != nil {
+			panic(fmt.Sprintf("gziptemplate: unexpected error from TagFunc: %s", err))
+		}
+
 		return append([]byte(nil), t.template...)
 	}
 
+	if t.patch != nil {
+		b, err := t.patch.execute(t, f)
+		if err == nil {
+			err = t.checkOutput(b)
+		}
+		if err != nil {
+			panic(fmt.Sprintf("gziptemplate: unexpected error from TagFunc: %s", err))
+		}
+
+		return b
+	}
+
 	b := gzipbuilder.NewBuilder(t.level)
-	uw := b.UncompressedWriter()
+	s := stream{
+		add: func(d *gzipbuilder.PrecompressedData) { b.AddPrecompressedData(d) },
+		w:   b.UncompressedWriter(),
+	}
 
-	for i := 0; i < n; i++ {
-		b.AddPrecompressedData(t.texts[i])
+	if err := t.execute(s, f); err != nil {
+		panic(fmt.Sprintf("gziptemplate: unexpected error from TagFunc: %s", err))
+	}
 
-		if err := f(uw, t.tags[i]); err != nil {
-			panic(fmt.Sprintf("gziptemplate: unexpected error from TagFunc: %s", err))
-		}
+	out := b.BytesOrPanic()
+	if err := t.checkOutput(out); err != nil {
+		panic(fmt.Sprintf("gziptemplate: unexpected error from TagFunc: %s", err))
+	}
+
+	return out
+}
+
+// ExecuteFuncBytesErr is like ExecuteFuncBytes but returns any error from f,
+// or from an unsupported substitution value, rather than panicking.
+func (t *Template) ExecuteFuncBytesErr(f TagFunc) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := t.ExecuteFunc(&buf, f); err != nil {
+		return nil, err
 	}
 
-	b.AddPrecompressedData(t.texts[n])
-	return b.BytesOrPanic()
+	return buf.Bytes(), nil
+}
+
+// ExecuteBytesErr is like ExecuteBytes but returns an error for unsupported
+// substitution values, or from TagFunc values, rather than panicking.
+func (t *Template) ExecuteBytesErr(m map[string]interface{}) ([]byte, error) {
+	return t.ExecuteFuncBytesErr(t.mapTagFunc(m))
 }
 
 // ExecuteBytes substitutes template tags (placeholders) with the corresponding
@@ -212,17 +723,215 @@ func (t *Template) ExecuteFuncBytes(f TagFunc) []byte {
 //   * []byte - the fastest value type
 //   * string - convenient value type
 //   * TagFunc - flexible value type
+//   * TagWriterFunc - TagFunc that also returns the number of bytes written
+//   * *Value - precompressed value, see NewValue
+//   * Escaped - value escaped when written, see HTML and URLQuery
+//   * []string, [][]byte - elements separated by commas, see WithSeparator
+//   * Joined - elements separated by a given separator
+//   * func() []byte - called at most once per execution for each tag
+//   * func(tag string) ([]byte, error) - called for each tag occurrence
+//   * int, int64, uint64, float64 - formatted with strconv
+//   * bool - written as true or false, or the condition of an #if section
+//   * encoding.TextMarshaler - written as its text encoding
+//   * fmt.Stringer - written as returned by String
+//   * json.Marshaler - written as its JSON encoding
+//   * io.WriterTo, io.Reader - streamed until EOF, consuming the value
+//   * *Template - nested template sharing the same values, see Bind
+//
+// It panics if a value is of an unsupported type or a TagFunc returns an
+// error, see ExecuteBytesErr.
 func (t *Template) ExecuteBytes(m map[string]interface{}) []byte {
-	return t.ExecuteFuncBytes(func(w io.Writer, tag string) error {
-		return stdTagFunc(w, tag, m)
-	})
+	return t.ExecuteFuncBytes(t.mapTagFunc(m))
+}
+
+// ExecuteStd is like Execute but tags without a value in m, or a default,
+// are written verbatim with their delimiters rather than being dropped. They
+// are written as they appear in the source, filters included, without
+// escaping.
+//
+// This allows multi-pass pipelines where a later stage substitutes the
+// remaining tags. Templates created with a TemplateBuilder have no
+// delimiters, so only the tag name is written.
+func (t *Template) ExecuteStd(w io.Writer, m map[string]interface{}) error {
+	return t.ExecuteFunc(w, t.stdTagFunc(m))
+}
+
+// ExecuteStdBytes is like ExecuteBytes but tags without a value in m, or a
+// default, are written verbatim with their delimiters rather than being
+// dropped.
+//
+// See ExecuteStd for details.
+func (t *Template) ExecuteStdBytes(m map[string]interface{}) []byte {
+	return t.ExecuteFuncBytes(t.stdTagFunc(m))
+}
+
+// stdTagFunc returns a TagFunc that substitutes values from m, returning
+// errVerbatim for tags without a value.
+func (t *Template) stdTagFunc(m map[string]interface{}) TagFunc {
+	f := t.mapTagFunc(m)
+	return func(w io.Writer, tag string) error {
+		if isControlWriter(w) {
+			return f(w, tag)
+		}
+
+		if v, _ := resolve(t.mapLookup(m), tag); v != nil || t.defaults[tag] != nil {
+			return f(w, tag)
+		}
+
+		return errVerbatim
+	}
+}
+
+// errVerbatim is returned by the TagFunc of ExecuteStd for a tag without a
+// value, which is then written as it appears in the source, bypassing the
+// filters and escaping of the tag.
+var errVerbatim = errors.New("gziptemplate: tag written verbatim")
+
+// writeSource writes the source text of the i'th tag of t to w.
+func (t *Template) writeSource(w io.Writer, i int) error {
+	src := t.tags[i].src
+	if src == "" {
+		src = t.startTag + t.tags[i].name + t.endTag
+	}
+
+	_, err := io.WriteString(w, src)
+	return err
 }
 
-func stdTagFunc(w io.Writer, tag string, m map[string]interface{}) error {
-	v := m[tag]
+// mapTagFunc returns a TagFunc that substitutes values from m.
+func (t *Template) mapTagFunc(m map[string]interface{}) TagFunc {
+	return t.lookupTagFunc(t.mapLookup(m))
+}
+
+// mapLookup returns a function that looks up values in m, without regard to
+// case if the template was compiled with WithCaseInsensitive.
+func (t *Template) mapLookup(m map[string]interface{}) func(tag string) (interface{}, bool) {
+	if t.foldCase {
+		return foldLookup(m)
+	}
+
+	return mapLookup(m)
+}
+
+// foldLookup returns a function that looks up values in m, falling back to
+// a key that matches without regard to case.
+func foldLookup(m map[string]interface{}) func(tag string) (interface{}, bool) {
+	var (
+		once   sync.Once
+		folded map[string]interface{}
+	)
+	return func(tag string) (interface{}, bool) {
+		if v, ok := m[tag]; ok {
+			return v, true
+		}
+
+		// The keys are only folded once a tag misses.
+		once.Do(func() {
+			folded = make(map[string]interface{}, len(m))
+			for k, v := range m {
+				folded[strings.ToLower(k)] = v
+			}
+		})
+
+		v, ok := folded[strings.ToLower(tag)]
+		return v, ok
+	}
+}
+
+// mapLookup returns a function that looks up values in m.
+func mapLookup(m map[string]interface{}) func(tag string) (interface{}, bool) {
+	return func(tag string) (interface{}, bool) {
+		v, ok := m[tag]
+		return v, ok
+	}
+}
+
+// lookupTagFunc returns a TagFunc that substitutes the values returned by
+// lookup, falling back to the template's defaults.
+func (t *Template) lookupTagFunc(lookup func(tag string) (interface{}, bool)) TagFunc {
+	return t.lazyTagFunc(lookup, new(lazyValues))
+}
+
+// lazyTagFunc is like lookupTagFunc but remembers the results of func()
+// []byte values in lazy, which may be shared with nested templates.
+func (t *Template) lazyTagFunc(lookup func(tag string) (interface{}, bool), lazy *lazyValues) TagFunc {
+	return func(w io.Writer, tag string) error {
+		v, ok := resolve(lookup, tag)
+		if !ok {
+			v = t.defaults[tag]
+		}
+		if !isHasWriter(w) {
+			v = lazy.eval(tag, v)
+		}
+
+		if v == nil && t.missing != nil && !isControlWriter(w) {
+			return t.missing(w, tag)
+		}
+
+		if nt, ok := v.(*Template); ok && !isHasWriter(w) {
+			return nt.writeTo(w, nt.lazyTagFunc(lookup, lazy))
+		}
+
+		return writeValue(w, tag, t.templateValue(tag, v))
+	}
+}
+
+// lazyValues holds the results of the func() []byte values of an execution
+// by tag, so that each is called at most once however often its tag occurs.
+type lazyValues map[string][]byte
+
+// eval returns the result of v for tag if it is a func() []byte, calling it
+// only if it hasn't been already. Other values are returned as is.
+func (lv *lazyValues) eval(tag string, v interface{}) interface{} {
+	f, ok := v.(func() []byte)
+	if !ok {
+		return v
+	}
+
+	if b, ok := (*lv)[tag]; ok {
+		return b
+	}
+
+	b := f()
+	if *lv == nil {
+		*lv = make(lazyValues)
+	}
+	(*lv)[tag] = b
+	return b
+}
+
+// unsupportedValue returns the error for a substitution value of an
+// unsupported type.
+func unsupportedValue(tag string, v interface{}) error {
+	return &valueTypeError{tag, v}
+}
+
+// valueTypeError is returned for a substitution value of an unsupported
+// type.
+type valueTypeError struct {
+	tag string
+	v   interface{}
+}
+
+func (e *valueTypeError) Error() string {
+	return fmt.Sprintf("gziptemplate: tag=%q %s", e.tag, e.detail())
+}
+
+// detail describes the error without naming the tag, for an *ExecError
+// that already does.
+func (e *valueTypeError) detail() string {
+	return fmt.Sprintf("contains unexpected value type=%#v", e.v)
+}
+
+// writeValue writes the substitution value v for tag to w.
+func writeValue(w io.Writer, tag string, v interface{}) error {
 	if v == nil {
 		return nil
 	}
+	if hw, ok := w.(*hasWriter); ok {
+		hw.ok = true
+		return nil
+	}
 	switch value := v.(type) {
 	case []byte:
 		_, err := w.Write(value)
@@ -231,8 +940,110 @@ func stdTagFunc(w io.Writer, tag string, m map[string]interface{}) error {
 		_, err := w.Write([]byte(value))
 		return err
 	case TagFunc:
+		if holdsCondition(w) {
+			return nil
+		}
+
 		return value(w, tag)
+	case TagWriterFunc:
+		if holdsCondition(w) {
+			return nil
+		}
+
+		_, err := value(w, tag)
+		return err
+	case func() []byte:
+		_, err := w.Write(value())
+		return err
+	case func(tag string) ([]byte, error):
+		if holdsCondition(w) {
+			return nil
+		}
+
+		b, err := value(tag)
+		if err != nil {
+			return err
+		}
+
+		_, err = w.Write(b)
+		return err
+	case *Value:
+		return value.writeTo(w)
+	case Escaped:
+		return value.writeTo(w, tag)
+	case Joined:
+		return value.writeTo(w, tag)
+	case []string, [][]byte:
+		return Joined{value, defaultSeparator}.writeTo(w, tag)
+	case bool:
+		if cw, ok := w.(*condWriter); ok {
+			cw.ok = value
+			return nil
+		}
+
+		var buf [8]byte
+		_, err := w.Write(strconv.AppendBool(buf[:0], value))
+		return err
+	case int:
+		var buf [24]byte
+		_, err := w.Write(strconv.AppendInt(buf[:0], int64(value), 10))
+		return err
+	case int64:
+		var buf [24]byte
+		_, err := w.Write(strconv.AppendInt(buf[:0], value, 10))
+		return err
+	case uint64:
+		var buf [24]byte
+		_, err := w.Write(strconv.AppendUint(buf[:0], value, 10))
+		return err
+	case float64:
+		var buf [32]byte
+		_, err := w.Write(strconv.AppendFloat(buf[:0], value, 'g', -1, 64))
+		return err
+	case []map[string]interface{}:
+		if rw, ok := w.(*rangeWriter); ok {
+			rw.items = value
+			return nil
+		}
+		if cw, ok := w.(*condWriter); ok {
+			cw.ok = len(value) != 0
+			return nil
+		}
+		return unsupportedValue(tag, v)
+	case encoding.TextMarshaler:
+		b, err := value.MarshalText()
+		if err != nil {
+			return err
+		}
+
+		_, err = w.Write(b)
+		return err
+	case fmt.Stringer:
+		_, err := io.WriteString(w, value.String())
+		return err
+	case json.Marshaler:
+		b, err := value.MarshalJSON()
+		if err != nil {
+			return err
+		}
+
+		_, err = w.Write(b)
+		return err
+	case io.WriterTo:
+		if holdsCondition(w) {
+			return nil
+		}
+
+		_, err := value.WriteTo(w)
+		return err
+	case io.Reader:
+		if holdsCondition(w) {
+			return nil
+		}
+
+		_, err := io.Copy(w, value)
+		return err
 	default:
-		panic(fmt.Sprintf("gziptemplate: tag=%q contains unexpected value type=%#v", tag, v))
+		return unsupportedValue(tag, v)
 	}
 }
@@ -3,10 +3,15 @@ package gziptemplate
 import (
 	"bytes"
 	"compress/gzip"
+	"encoding/json"
+	"errors"
 	"io"
 	"io/ioutil"
+	"net"
 	"strings"
 	"testing"
+	"testing/iotest"
+	"time"
 )
 
 func decompressBytes(t *testing.T, b []byte) []byte {
@@ -205,12 +210,142 @@ func TestUnsupportedValue(t *testing.T) {
 	tpl := New(template, "[", "]", BestCompression)
 
 	expectPanic(t, func() {
-		tpl.ExecuteBytes(map[string]interface{}{"foo": 123, "aaa": "bbb"})
+		tpl.ExecuteBytes(map[string]interface{}{"foo": []int{123}, "aaa": "bbb"})
 	})
 }
 
+func TestNumericValues(t *testing.T) {
+	tpl := New("[int] [int64] [uint64] [float64] [bool] [#if cond]yes[#end]", "[", "]", BestCompression)
+
+	s := tpl.ExecuteBytes(map[string]interface{}{
+		"int":     -42,
+		"int64":   int64(1) << 40,
+		"uint64":  uint64(1) << 63,
+		"float64": 2.5,
+		"bool":    false,
+		"cond":    false,
+	})
+	s = decompressBytes(t, s)
+	result := "-42 1099511627776 9223372036854775808 2.5 false "
+	if string(s) != result {
+		t.Fatalf("unexpected template value %q. Expected %q", s, result)
+	}
+}
+
+func TestReaderValues(t *testing.T) {
+	tpl := New("[reader]|[writerto]", "[", "]", BestCompression)
+
+	big := strings.Repeat("0123456789", 1<<12)
+	s := tpl.ExecuteBytes(map[string]interface{}{
+		"reader":   iotest.OneByteReader(strings.NewReader(big)),
+		"writerto": bytes.NewBufferString("buffered"),
+	})
+	s = decompressBytes(t, s)
+	result := big + "|buffered"
+	if string(s) != result {
+		t.Fatalf("unexpected template value %q. Expected %q", s, result)
+	}
+
+	errRead := errors.New("read error")
+	err := tpl.Execute(ioutil.Discard, map[string]interface{}{
+		"reader": iotest.ErrReader(errRead),
+	})
+	if !errors.Is(err, errRead) {
+		t.Fatalf("unexpected error %v. Expected %v", err, errRead)
+	}
+}
+
+func TestLazyValues(t *testing.T) {
+	tpl := New("[a]-[#if a][a][#end]-[nested]-[#range items][b][b][#end]", "[", "]", BestCompression)
+
+	calls := map[string]int{}
+	lazy := func(name, value string) func() []byte {
+		return func() []byte {
+			calls[name]++
+			return []byte(value)
+		}
+	}
+
+	for i := 0; i < 2; i++ {
+		s := tpl.ExecuteBytes(map[string]interface{}{
+			"a":      lazy("a", "x"),
+			"nested": New("<[a]>", "[", "]", BestSpeed),
+			"items": []map[string]interface{}{
+				{"b": lazy("b1", "y")},
+				{"b": lazy("b2", "z")},
+			},
+		})
+		s = decompressBytes(t, s)
+		result := "x-x-<x>-yyzz"
+		if string(s) != result {
+			t.Fatalf("unexpected template value %q. Expected %q", s, result)
+		}
+	}
+
+	expect := map[string]int{"a": 2, "b1": 2, "b2": 2}
+	for name, n := range expect {
+		if calls[name] != n {
+			t.Fatalf("unexpected number of calls %d for %q. Expected %d", calls[name], name, n)
+		}
+	}
+}
+
+func TestProducerFuncValues(t *testing.T) {
+	tpl := New("[a]|[b]", "[", "]", BestCompression)
+
+	produce := func(tag string) ([]byte, error) {
+		return []byte("<" + tag + ">"), nil
+	}
+	s := tpl.ExecuteBytes(map[string]interface{}{"a": produce, "b": produce})
+	s = decompressBytes(t, s)
+	result := "<a>|<b>"
+	if string(s) != result {
+		t.Fatalf("unexpected template value %q. Expected %q", s, result)
+	}
+
+	errProduce := errors.New("produce error")
+	err := tpl.Execute(ioutil.Discard, map[string]interface{}{
+		"a": func(tag string) ([]byte, error) { return nil, errProduce },
+	})
+	if !errors.Is(err, errProduce) {
+		t.Fatalf("unexpected error %v. Expected %v", err, errProduce)
+	}
+}
+
+func TestExecuteBytesErr(t *testing.T) {
+	tpl := New("foobar[foo]", "[", "]", BestCompression)
+
+	if _, err := tpl.ExecuteBytesErr(map[string]interface{}{"foo": []int{123}}); err == nil {
+		t.Fatal("expected error for unsupported value type")
+	}
+
+	errTag := errors.New("tag error")
+	_, err := tpl.ExecuteFuncBytesErr(func(w io.Writer, tag string) error {
+		return errTag
+	})
+	if !errors.Is(err, errTag) {
+		t.Fatalf("unexpected error %v. Expected %v", err, errTag)
+	}
+
+	b, err := tpl.ExecuteBytesErr(map[string]interface{}{"foo": "111"})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if s := decompressBytes(t, b); string(s) != "foobar111" {
+		t.Fatalf("unexpected template value %q. Expected %q", s, "foobar111")
+	}
+
+	b, err = New("static", "[", "]", BestCompression).ExecuteBytesErr(nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if s := decompressBytes(t, b); string(s) != "static" {
+		t.Fatalf("unexpected template value %q. Expected %q", s, "static")
+	}
+}
+
 func TestMixedValues(t *testing.T) {
-	template := "foo[foo]bar[bar]baz[baz]"
+	template := "foo[foo]bar[bar]baz[baz]qux[qux]"
 	tpl := New(template, "[", "]", BestCompression)
 
 	s := tpl.ExecuteBytes(map[string]interface{}{
@@ -220,9 +355,12 @@ func TestMixedValues(t *testing.T) {
 			_, err := io.WriteString(w, tag)
 			return err
 		}),
+		"qux": TagWriterFunc(func(w io.Writer, tag string) (int, error) {
+			return io.WriteString(w, tag)
+		}),
 	})
 	s = decompressBytes(t, s)
-	result := "foo111barbbbbazbaz"
+	result := "foo111barbbbbazbazquxqux"
 	if string(s) != result {
 		t.Fatalf("unexpected template value %q. Expected %q", s, result)
 	}
@@ -251,3 +389,335 @@ func expectPanic(t *testing.T, f func()) {
 	}()
 	f()
 }
+
+func TestNewTemplateFromReader(t *testing.T) {
+	// Place delimiters so that they straddle the parser's buffer.
+	prefix := strings.Repeat("a", parseBufferSize-1)
+	template := prefix + "{{foo}}" + prefix + "x{{bar}}b{{baz"
+	template += strings.Repeat("z", parseBufferSize) + "}}c"
+
+	for _, r := range []io.Reader{
+		strings.NewReader(template),
+		iotest.OneByteReader(strings.NewReader(template)),
+	} {
+		tpl, err := NewTemplateFromReader(r, "{{", "}}", BestCompression)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+
+		s := tpl.ExecuteBytes(map[string]interface{}{
+			"foo": "111",
+			"bar": "222",
+			"baz" + strings.Repeat("z", parseBufferSize): "333",
+		})
+		s = decompressBytes(t, s)
+		result := prefix + "111" + prefix + "x222b333c"
+		if string(s) != result {
+			t.Fatal("unexpected template value")
+		}
+	}
+}
+
+func TestNewTemplateFromReaderError(t *testing.T) {
+	errRead := errors.New("read error")
+	r := io.MultiReader(strings.NewReader("foo[bar"), &errReader{errRead})
+	if _, err := NewTemplateFromReader(r, "[", "]", BestCompression); err != errRead {
+		t.Fatalf("unexpected error %v. Expected %v", err, errRead)
+	}
+}
+
+type errReader struct{ err error }
+
+func (r *errReader) Read(p []byte) (int, error) { return 0, r.err }
+
+func TestNewTemplateFromChunks(t *testing.T) {
+	tpl, err := NewTemplateFromChunks([]io.Reader{
+		strings.NewReader("<header>[foo]</header>"),
+		strings.NewReader("<body>[b"),
+		strings.NewReader("ar]</body>"),
+		strings.NewReader(""),
+		strings.NewReader("<footer/>"),
+	}, "[", "]", BestCompression)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	s := tpl.ExecuteBytes(map[string]interface{}{"foo": "111", "bar": "222"})
+	s = decompressBytes(t, s)
+	result := "<header>111</header><body>222</body><footer/>"
+	if string(s) != result {
+		t.Fatalf("unexpected template value %q. Expected %q", s, result)
+	}
+}
+
+func TestSetDefault(t *testing.T) {
+	tpl := New("[site] - [title] <[email]>", "[", "]", BestCompression)
+	tpl.SetDefault("site", "Example")
+	tpl.SetDefault("email", "support@example.com")
+	tpl.SetDefault("title", "Untitled")
+	tpl.SetDefault("title", nil)
+
+	s := decompressBytes(t, tpl.ExecuteBytes(map[string]interface{}{
+		"email": "help@example.com",
+	}))
+	result := "Example -  <help@example.com>"
+	if string(s) != result {
+		t.Fatalf("unexpected template value %q. Expected %q", s, result)
+	}
+
+	s = decompressBytes(t, tpl.ExecuteProviderBytes(NewConfigProvider(nil)))
+	result = "Example -  <support@example.com>"
+	if string(s) != result {
+		t.Fatalf("unexpected template value %q. Expected %q", s, result)
+	}
+}
+
+func TestNewTemplateBytes(t *testing.T) {
+	template := []byte("\x00\x01\xff\xfefoo\xfe\xff\x80\xff\xfeb\x80r\xfe\xff\x00")
+	tpl, err := NewTemplateBytes(template, []byte("\xff\xfe"), []byte("\xfe\xff"), BestCompression)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	s := tpl.ExecuteBytes(map[string]interface{}{
+		"foo":    []byte{0xc0, 0x00},
+		"b\x80r": "\x80",
+	})
+	s = decompressBytes(t, s)
+	result := "\x00\x01\xc0\x00\x80\x80\x00"
+	if string(s) != result {
+		t.Fatalf("unexpected template value %q. Expected %q", s, result)
+	}
+}
+
+type jsonValue struct{ err error }
+
+func (v jsonValue) MarshalJSON() ([]byte, error) {
+	return []byte(`{"a":1}`), v.err
+}
+
+func TestJSONMarshalerValue(t *testing.T) {
+	template := "[foo],[bar]"
+	tpl := New(template, "[", "]", BestCompression)
+
+	s := tpl.ExecuteBytes(map[string]interface{}{
+		"foo": jsonValue{},
+		"bar": json.RawMessage(`[1,2]`),
+	})
+	s = decompressBytes(t, s)
+	result := `{"a":1},[1,2]`
+	if string(s) != result {
+		t.Fatalf("unexpected template value %q. Expected %q", s, result)
+	}
+
+	errMarshal := errors.New("marshal error")
+	err := tpl.Execute(ioutil.Discard, map[string]interface{}{
+		"foo": jsonValue{errMarshal},
+	})
+	if !errors.Is(err, errMarshal) {
+		t.Fatalf("unexpected error %v. Expected %v", err, errMarshal)
+	}
+}
+
+func TestTextMarshalerStringerValue(t *testing.T) {
+	tpl := New("[ip] [dur] [time] [missing]", "[", "]", BestCompression)
+
+	s := tpl.ExecuteStdBytes(map[string]interface{}{
+		"ip":   net.IPv4(192, 0, 2, 1),
+		"dur":  90 * time.Second,
+		"time": time.Date(2009, time.November, 10, 23, 0, 0, 0, time.UTC),
+	})
+	s = decompressBytes(t, s)
+	result := "192.0.2.1 1m30s 2009-11-10T23:00:00Z [missing]"
+	if string(s) != result {
+		t.Fatalf("unexpected template value %q. Expected %q", s, result)
+	}
+
+	err := tpl.Execute(ioutil.Discard, map[string]interface{}{
+		"ip": net.IP{1},
+	})
+	if err == nil {
+		t.Fatal("expected error for invalid net.IP")
+	}
+}
+
+func TestTagDefaults(t *testing.T) {
+	template := "<title>[title|Untitled]</title>[empty|none][fn|fn default]"
+	tpl := New(template, "[", "]", BestCompression, WithEscaping(map[string]Escaping{
+		"title": EscapeHTML,
+	}))
+
+	for _, test := range []struct {
+		m      map[string]interface{}
+		result string
+	}{
+		{map[string]interface{}{"title": "<a>", "empty": ""}, "<title>&lt;a&gt;</title>fn default"},
+		{map[string]interface{}{"fn": TagFunc(func(w io.Writer, tag string) error {
+			_, err := io.WriteString(w, "111")
+			return err
+		})}, "<title>Untitled</title>none111"},
+		{nil, "<title>Untitled</title>nonefn default"},
+	} {
+		s := decompressBytes(t, tpl.ExecuteBytes(test.m))
+		if string(s) != test.result {
+			t.Fatalf("unexpected template value %q. Expected %q", s, test.result)
+		}
+	}
+
+	tpl.SetDefault("title", "Site")
+	s := decompressBytes(t, tpl.ExecuteBytes(nil))
+	result := "<title>Site</title>nonefn default"
+	if string(s) != result {
+		t.Fatalf("unexpected template value %q. Expected %q", s, result)
+	}
+}
+
+func TestComments(t *testing.T) {
+	template := "foo[# a comment #]bar[#\tmulti\nline [foo] comment ]#][foo][# #]baz"
+	tpl := New(template, "[", "]", BestCompression)
+	if len(tpl.tags) != 1 {
+		t.Fatalf("unexpected number of tags %d. Expected %d", len(tpl.tags), 1)
+	}
+
+	s := decompressBytes(t, tpl.ExecuteBytes(map[string]interface{}{"foo": "111"}))
+	result := "foobar111baz"
+	if string(s) != result {
+		t.Fatalf("unexpected template value %q. Expected %q", s, result)
+	}
+
+	for _, template := range []string{
+		"foo[# unterminated",
+		"foo[# unterminated ]",
+	} {
+		if _, err := NewTemplate(template, "[", "]", BestCompression); err == nil {
+			t.Fatalf("expected non-nil error for %q. got nil", template)
+		}
+	}
+}
+
+func TestRawSections(t *testing.T) {
+	template := "<script>[#raw]if (a[0]) { b = [#end]; }[#endraw]</script>[foo][#if x][#raw][x][#endraw][#end]"
+	tpl := New(template, "[", "]", BestCompression)
+	if len(tpl.tags) != 3 {
+		t.Fatalf("unexpected number of tags %d. Expected %d", len(tpl.tags), 3)
+	}
+
+	s := decompressBytes(t, tpl.ExecuteBytes(map[string]interface{}{"foo": "111", "x": true}))
+	result := "<script>if (a[0]) { b = [#end]; }</script>111[x]"
+	if string(s) != result {
+		t.Fatalf("unexpected template value %q. Expected %q", s, result)
+	}
+
+	for _, template := range []string{
+		"foo[#raw]unterminated",
+		"foo[#raw]unterminated[#end]",
+		"foo[#raw x]bar[#endraw]",
+	} {
+		if _, err := NewTemplate(template, "[", "]", BestCompression); err == nil {
+			t.Fatalf("expected non-nil error for %q. got nil", template)
+		}
+	}
+}
+
+func TestEscapedDelimiters(t *testing.T) {
+	for _, test := range []struct {
+		template, start, end, result string
+	}{
+		{"a[[b][foo][[[foo]]", "[", "]", "a[b]111[111]"},
+		{"{{{{{{foo}}}{{{foo}}}", "{{{", "}}}", "{{{foo}}}111"},
+		{"@@foo", "@", "@", "foo"},
+	} {
+		tpl := New(test.template, test.start, test.end, BestCompression)
+
+		s := decompressBytes(t, tpl.ExecuteBytes(map[string]interface{}{"foo": "111"}))
+		if string(s) != test.result {
+			t.Fatalf("unexpected template value %q. Expected %q", s, test.result)
+		}
+	}
+}
+
+func TestTrimMarkers(t *testing.T) {
+	template := "<ul>\n\t[- #range items -]\n\t<li>\n\t\t[- name -]\n\t</li>\n\t[- #end -]\n</ul> [- x]\n"
+	tpl := New(template, "[", "]", BestCompression)
+
+	s := decompressBytes(t, tpl.ExecuteBytes(map[string]interface{}{
+		"items": []map[string]interface{}{{"name": "a"}, {"name": "b"}},
+		"x":     " 1 ",
+	}))
+	result := "<ul><li>a</li><li>b</li></ul> 1 \n"
+	if string(s) != result {
+		t.Fatalf("unexpected template value %q. Expected %q", s, result)
+	}
+}
+
+func TestExecuteStd(t *testing.T) {
+	template := "{{foo}}.{{bar}}.{{baz|x}}.{{#if qux}}q{{#end}}"
+	tpl := New(template, "{{", "}}", BestCompression)
+	tpl.SetDefault("baz", "333")
+
+	m := map[string]interface{}{"foo": "111"}
+	result := "111.{{bar}}.333."
+
+	s := decompressBytes(t, tpl.ExecuteStdBytes(m))
+	if string(s) != result {
+		t.Fatalf("unexpected template value %q. Expected %q", s, result)
+	}
+
+	var buf bytes.Buffer
+	if err := tpl.ExecuteStd(&buf, m); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if s := decompressBytes(t, buf.Bytes()); string(s) != result {
+		t.Fatalf("unexpected template value %q. Expected %q", s, result)
+	}
+}
+
+func TestExecuteStdFilters(t *testing.T) {
+	tpl := New(`<a href="?q=[q|urlquery]">[h]</a>[q|html]`, "[", "]", BestCompression, WithEscaping(map[string]Escaping{
+		"h": EscapeHTML,
+	}))
+
+	s := decompressBytes(t, tpl.ExecuteStdBytes(map[string]interface{}{"q": "a b"}))
+	result := `<a href="?q=a+b">[h]</a>a b`
+	if string(s) != result {
+		t.Fatalf("unexpected template value %q. Expected %q", s, result)
+	}
+
+	s = decompressBytes(t, tpl.ExecuteStdBytes(nil))
+	result = `<a href="?q=[q|urlquery]">[h]</a>[q|html]`
+	if string(s) != result {
+		t.Fatalf("unexpected template value %q. Expected %q", s, result)
+	}
+}
+
+func TestSource(t *testing.T) {
+	template := "foo{{bar}}baz"
+	tpl := New(template, "{{", "}}", BestCompression)
+	if s := tpl.Source(); s != template {
+		t.Fatalf("unexpected source %q. Expected %q", s, template)
+	}
+	if start, end := tpl.Delims(); start != "{{" || end != "}}" {
+		t.Fatalf("unexpected delimiters %q and %q. Expected %q and %q", start, end, "{{", "}}")
+	}
+
+	tpl, err := NewTemplateFromReader(strings.NewReader(template), "{{", "}}", BestCompression)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if s := tpl.Source(); s != "" {
+		t.Fatalf("unexpected source %q. Expected none", s)
+	}
+
+	tpl, err = NewTemplateFromReader(strings.NewReader(template), "{{", "}}", BestCompression, WithSource())
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if s := tpl.Source(); s != template {
+		t.Fatalf("unexpected source %q. Expected %q", s, template)
+	}
+
+	tpl = New(template, "{{", "}}", BestCompression, WithoutSource())
+	if s := tpl.Source(); s != "" {
+		t.Fatalf("unexpected source %q. Expected none", s)
+	}
+}
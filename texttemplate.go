@@ -0,0 +1,110 @@
+package gziptemplate
+
+import (
+	"fmt"
+	"strings"
+	tparse "text/template/parse"
+)
+
+// FromTextTemplate compiles a template written in the common subset of the
+// text/template syntax:
+//   * {{.Field}} and {{.Field.Member}} substitute a value, see NewTemplate
+//     for how members are resolved
+//   * {{if .Field}}...{{else}}...{{end}}, including {{else if}}, are
+//     conditional sections
+//   * {{range .Field}}...{{end}} are repeated sections, within which fields
+//     refer to the items
+//   * comments and trim markers are supported
+//
+// Functions, variables, pipelines and the other actions are not supported and
+// are reported as errors. Values are not escaped, as with text/template,
+// unless an escaping policy is given with WithEscaping.
+func FromTextTemplate(src string, level int, opts ...Option) (*Template, error) {
+	trees, err := tparse.Parse("gziptemplate", src, "", "")
+	if err != nil {
+		return nil, err
+	}
+	if len(trees) > 1 {
+		return nil, fmt.Errorf("gziptemplate: {{define}} is not supported")
+	}
+
+	var b strings.Builder
+	if tree := trees["gziptemplate"]; tree != nil && tree.Root != nil {
+		if err := convertTextNode(&b, tree.Root); err != nil {
+			return nil, err
+		}
+	}
+
+	return NewTemplate(b.String(), "{{", "}}", level, opts...)
+}
+
+// convertTextNode writes the node of a text/template tree to b in the syntax
+// of NewTemplate, with "{{" and "}}" as delimiters.
+func convertTextNode(b *strings.Builder, node tparse.Node) error {
+	switch n := node.(type) {
+	case *tparse.ListNode:
+		for _, node := range n.Nodes {
+			if err := convertTextNode(b, node); err != nil {
+				return err
+			}
+		}
+	case *tparse.TextNode:
+		// Text can't contain the start delimiter, which would have begun
+		// an action.
+		b.Write(n.Text)
+	case *tparse.ActionNode:
+		name, err := textTemplateField(n.Pipe)
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintf(b, "{{%s}}", name)
+	case *tparse.IfNode:
+		return convertTextBranch(b, "if", &n.BranchNode)
+	case *tparse.RangeNode:
+		if n.ElseList != nil {
+			return fmt.Errorf("gziptemplate: {{else}} in {{range}} is not supported: %s", n)
+		}
+
+		return convertTextBranch(b, "range", &n.BranchNode)
+	default:
+		return fmt.Errorf("gziptemplate: unsupported text/template action: %s", node)
+	}
+
+	return nil
+}
+
+// convertTextBranch writes an if or range action to b.
+func convertTextBranch(b *strings.Builder, directive string, n *tparse.BranchNode) error {
+	name, err := textTemplateField(n.Pipe)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(b, "{{#%s %s}}", directive, name)
+	if err := convertTextNode(b, n.List); err != nil {
+		return err
+	}
+
+	if n.ElseList != nil {
+		b.WriteString("{{#else}}")
+		if err := convertTextNode(b, n.ElseList); err != nil {
+			return err
+		}
+	}
+
+	b.WriteString("{{#end}}")
+	return nil
+}
+
+// textTemplateField returns the name of the tag for a pipeline consisting of
+// a single field, such as .Field.Member.
+func textTemplateField(pipe *tparse.PipeNode) (string, error) {
+	if len(pipe.Decl) == 0 && len(pipe.Cmds) == 1 && len(pipe.Cmds[0].Args) == 1 {
+		if field, ok := pipe.Cmds[0].Args[0].(*tparse.FieldNode); ok {
+			return strings.Join(field.Ident, "."), nil
+		}
+	}
+
+	return "", fmt.Errorf("gziptemplate: unsupported text/template pipeline: %s", pipe)
+}
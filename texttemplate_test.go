@@ -0,0 +1,43 @@
+package gziptemplate
+
+import "testing"
+
+func TestFromTextTemplate(t *testing.T) {
+	src := "<h1>{{.Title}}</h1>{{/* a comment */}}\n{{- if .User.Name}} hi {{.User.Name}}{{else if .Guest}} guest{{else}} anon{{end}}\n" +
+		"<ul>{{range .Items}}<li>{{.Name}}</li>{{end}}</ul> }} done"
+	tpl, err := FromTextTemplate(src, BestCompression)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	s := decompressBytes(t, tpl.ExecuteBytes(map[string]interface{}{
+		"Title": "Hello",
+		"User":  map[string]interface{}{"Name": "Bob"},
+		"Items": []map[string]interface{}{{"Name": "a"}, {"Name": "b"}},
+	}))
+	result := "<h1>Hello</h1> hi Bob\n<ul><li>a</li><li>b</li></ul> }} done"
+	if string(s) != result {
+		t.Fatalf("unexpected template value %q. Expected %q", s, result)
+	}
+
+	s = decompressBytes(t, tpl.ExecuteBytes(map[string]interface{}{"Guest": true}))
+	result = "<h1></h1> guest\n<ul></ul> }} done"
+	if string(s) != result {
+		t.Fatalf("unexpected template value %q. Expected %q", s, result)
+	}
+
+	for _, src := range []string{
+		"{{.Foo | printf}}",
+		"{{$x := .Foo}}",
+		"{{with .Foo}}{{end}}",
+		"{{template \"x\"}}",
+		"{{define \"x\"}}{{end}}",
+		"{{range .Foo}}{{else}}{{end}}",
+		"{{.}}",
+		"{{if .Foo}}",
+	} {
+		if _, err := FromTextTemplate(src, BestCompression); err == nil {
+			t.Fatalf("expected non-nil error for %q. got nil", src)
+		}
+	}
+}
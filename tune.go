@@ -0,0 +1,83 @@
+package gziptemplate
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// recommendLevels are the levels considered by RecommendLevel, fastest
+// first.
+var recommendLevels = []int{
+	HuffmanOnly,
+	BestSpeed, 2, 3, 4, 5, 6, 7, 8,
+	BestCompression,
+}
+
+// RecommendLevel analyses a corpus of representative outputs, such as
+// template sources or sample renders, and recommends a compression level for
+// templates producing similar output.
+//
+// Each sample is compressed independently at every level, so that the
+// per-render overhead paid by many small outputs is taken into account. The
+// fastest level whose total compressed size is within tolerance (a fraction,
+// e.g. 0.01 for 1%) of the smallest total is returned.
+//
+// compress/flate does not allow custom Huffman tables, so the recommendation
+// is limited to choosing a level. It may be applied to the templates of a
+// TemplateSet with SetLevel.
+func RecommendLevel(samples [][]byte, tolerance float64) (int, error) {
+	if len(samples) == 0 {
+		return 0, errors.New("gziptemplate: no samples to analyse")
+	}
+	if tolerance < 0 || math.IsNaN(tolerance) {
+		return 0, fmt.Errorf("gziptemplate: invalid tolerance: %v", tolerance)
+	}
+
+	var cw countWriter
+	sizes := make([]int64, len(recommendLevels))
+	for i, level := range recommendLevels {
+		gw, err := gzip.NewWriterLevel(&cw, level)
+		if err != nil {
+			return 0, err
+		}
+
+		cw = 0
+		for _, sample := range samples {
+			gw.Reset(&cw)
+			if _, err := gw.Write(sample); err != nil {
+				return 0, err
+			}
+			if err := gw.Close(); err != nil {
+				return 0, err
+			}
+		}
+
+		sizes[i] = int64(cw)
+	}
+
+	best := sizes[0]
+	for _, size := range sizes[1:] {
+		if size < best {
+			best = size
+		}
+	}
+
+	limit := float64(best) * (1 + tolerance)
+	for i, size := range sizes {
+		if float64(size) <= limit {
+			return recommendLevels[i], nil
+		}
+	}
+
+	panic("unreachable")
+}
+
+// countWriter counts the bytes written to it and discards them.
+type countWriter int64
+
+func (cw *countWriter) Write(p []byte) (int, error) {
+	*cw += countWriter(len(p))
+	return len(p), nil
+}
@@ -0,0 +1,47 @@
+package gziptemplate
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestRecommendLevel(t *testing.T) {
+	samples := [][]byte{
+		[]byte(strings.Repeat("https://google.com/?q=hello%3Dworld&foo=foobarfoobar\n", 64)),
+		[]byte(strings.Repeat("Hello, John! You won $100500!!!\n", 64)),
+	}
+
+	level, err := RecommendLevel(samples, 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if level < HuffmanOnly || level > BestCompression {
+		t.Fatalf("unexpected level %d", level)
+	}
+
+	// Every level is within an unbounded tolerance of the best, so the
+	// fastest must be chosen.
+	level, err = RecommendLevel(samples, 1e9)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if level != HuffmanOnly {
+		t.Fatalf("unexpected level %d. Expected %d", level, HuffmanOnly)
+	}
+}
+
+func TestRecommendLevelNoSamples(t *testing.T) {
+	if _, err := RecommendLevel(nil, 0); err == nil {
+		t.Fatalf("expected non-nil error. got nil")
+	}
+}
+
+func TestRecommendLevelInvalidTolerance(t *testing.T) {
+	samples := [][]byte{[]byte("hello")}
+	for _, tolerance := range []float64{-0.1, math.NaN()} {
+		if _, err := RecommendLevel(samples, tolerance); err == nil {
+			t.Fatalf("expected non-nil error for tolerance %v. got nil", tolerance)
+		}
+	}
+}
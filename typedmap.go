@@ -0,0 +1,61 @@
+package gziptemplate
+
+import "io"
+
+// ExecuteBytesMap is like Execute but takes a map of []byte values, which
+// are written without the type switch needed for values of other types.
+// Tags without a value in m fall back to the template's defaults.
+func (t *Template) ExecuteBytesMap(w io.Writer, m map[string][]byte) error {
+	return t.ExecuteLookup(w, func(tag string) ([]byte, bool) {
+		v, ok := m[tag]
+		return v, ok
+	})
+}
+
+// ExecuteLookup is like Execute but takes the value of each tag from lookup,
+// which reports whether the tag has a value. Tags without a value fall back to
+// the template's defaults.
+//
+// This suits data sources other than maps, such as caches and request
+// contexts, without first copying their values into a map.
+func (t *Template) ExecuteLookup(w io.Writer, lookup func(tag string) ([]byte, bool)) error {
+	missing := t.lookupTagFunc(noLookup)
+	return t.ExecuteFunc(w, func(w io.Writer, tag string) error {
+		v, ok := lookup(tag)
+		if !ok {
+			return missing(w, tag)
+		}
+		if hw, ok := w.(*hasWriter); ok {
+			hw.ok = true
+			return nil
+		}
+
+		_, err := w.Write(v)
+		return err
+	})
+}
+
+// ExecuteStringMap is like Execute but takes a map of string values, which
+// are written without the type switch needed for values of other types.
+// Tags without a value in m fall back to the template's defaults.
+func (t *Template) ExecuteStringMap(w io.Writer, m map[string]string) error {
+	missing := t.lookupTagFunc(noLookup)
+	return t.ExecuteFunc(w, func(w io.Writer, tag string) error {
+		v, ok := m[tag]
+		if !ok {
+			return missing(w, tag)
+		}
+		if hw, ok := w.(*hasWriter); ok {
+			hw.ok = true
+			return nil
+		}
+
+		_, err := io.WriteString(w, v)
+		return err
+	})
+}
+
+// noLookup is a lookup function that finds no values.
+func noLookup(tag string) (interface{}, bool) {
+	return nil, false
+}
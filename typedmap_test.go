@@ -0,0 +1,62 @@
+package gziptemplate
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestExecuteTypedMaps(t *testing.T) {
+	tpl := New("[foo] [#if bar]yes[#else]no[#end] [baz|def] [qux]", "[", "]", BestCompression)
+	tpl.SetDefault("qux", "dflt")
+
+	var buf bytes.Buffer
+	if err := tpl.ExecuteBytesMap(&buf, map[string][]byte{
+		"foo": []byte("111"),
+		"bar": []byte("x"),
+		"baz": {},
+	}); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	result := "111 yes  dflt"
+	if s := decompressBytes(t, buf.Bytes()); string(s) != result {
+		t.Fatalf("unexpected template value %q. Expected %q", s, result)
+	}
+
+	buf.Reset()
+	if err := tpl.ExecuteStringMap(&buf, map[string]string{
+		"foo": "222",
+		"bar": "",
+	}); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	result = "222 no def dflt"
+	if s := decompressBytes(t, buf.Bytes()); string(s) != result {
+		t.Fatalf("unexpected template value %q. Expected %q", s, result)
+	}
+}
+
+func TestExecuteLookup(t *testing.T) {
+	tpl := New("[foo]-[bar]-[baz]", "[", "]", BestCompression)
+	tpl.SetDefault("baz", "dflt")
+
+	var calls []string
+	var buf bytes.Buffer
+	err := tpl.ExecuteLookup(&buf, func(tag string) ([]byte, bool) {
+		calls = append(calls, tag)
+		if tag == "foo" {
+			return []byte("111"), true
+		}
+		return nil, false
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	result := "111--dflt"
+	if s := decompressBytes(t, buf.Bytes()); string(s) != result {
+		t.Fatalf("unexpected template value %q. Expected %q", s, result)
+	}
+	if len(calls) != 3 {
+		t.Fatalf("unexpected lookups %q", calls)
+	}
+}
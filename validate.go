@@ -0,0 +1,127 @@
+package gziptemplate
+
+import (
+	"sort"
+	"strings"
+)
+
+// ValidationError is returned by Validate and ValidateExact when a map does
+// not match the tags of a template.
+type ValidationError struct {
+	// Missing holds the tags without a value in the map.
+	Missing []string
+
+	// Unused holds the keys of the map not used by any tag. It is only
+	// set by ValidateExact.
+	Unused []string
+}
+
+func (e *ValidationError) Error() string {
+	var b strings.Builder
+	b.WriteString("gziptemplate: invalid substitution map:")
+	if len(e.Missing) != 0 {
+		b.WriteString(" missing values for tags ")
+		b.WriteString(strings.Join(e.Missing, ", "))
+	}
+	if len(e.Unused) != 0 {
+		if len(e.Missing) != 0 {
+			b.WriteString(";")
+		}
+		b.WriteString(" unused keys ")
+		b.WriteString(strings.Join(e.Unused, ", "))
+	}
+	return b.String()
+}
+
+// Validate checks that m has a value for every tag of t, returning a
+// *ValidationError listing those that don't. Tags with a default, either set
+// with SetDefault or given in the template, are exempt, as are the conditions
+// of conditional and repeated sections and the tags within repeated
+// sections.
+//
+// This allows a missing value to be caught at startup rather than silently
+// substituted as empty.
+func (t *Template) Validate(m map[string]interface{}) error {
+	return t.validate(m, false)
+}
+
+// ValidateExact is like Validate but also reports the keys of m not used by
+// any tag of t.
+func (t *Template) ValidateExact(m map[string]interface{}) error {
+	return t.validate(m, true)
+}
+
+func (t *Template) validate(m map[string]interface{}, exact bool) error {
+	var (
+		e       ValidationError
+		checked = make(map[string]bool)
+
+		// optional is the index of a tag with a default in the
+		// template.
+		optional = -1
+
+		// ranges holds the ends of the repeated sections being
+		// walked.
+		ranges []int
+	)
+	for i, tag := range t.tags {
+		for len(ranges) != 0 && ranges[len(ranges)-1] <= i {
+			ranges = ranges[:len(ranges)-1]
+		}
+
+		switch tag.op {
+		case opRange:
+			ranges = append(ranges, tag.jump)
+			continue
+		case opHas:
+			optional = i + 1
+			continue
+		case opValue:
+		default:
+			continue
+		}
+
+		if len(ranges) != 0 || i == optional || checked[tag.name] {
+			continue
+		}
+		checked[tag.name] = true
+
+		if _, ok := t.defaults[tag.name]; ok {
+			continue
+		}
+		if _, ok := resolve(t.mapLookup(m), tag.name); !ok {
+			e.Missing = append(e.Missing, tag.name)
+		}
+	}
+
+	if exact {
+		used := make(map[string]bool, len(t.tags))
+		for _, tag := range t.tags {
+			name := tag.name
+			if t.foldCase {
+				name = strings.ToLower(name)
+			}
+			used[name] = true
+			if i := strings.IndexByte(name, '.'); i >= 0 {
+				used[name[:i]] = true
+			}
+		}
+
+		for key := range m {
+			name := key
+			if t.foldCase {
+				name = strings.ToLower(name)
+			}
+
+			if !used[name] {
+				e.Unused = append(e.Unused, key)
+			}
+		}
+		sort.Strings(e.Unused)
+	}
+
+	if len(e.Missing) == 0 && len(e.Unused) == 0 {
+		return nil
+	}
+	return &e
+}
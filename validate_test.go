@@ -0,0 +1,64 @@
+package gziptemplate
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	tpl := New("[a] [b.c] [d|def] [#if cond][e][#end] [#range items][f][#end] [g] [a]", "[", "]", BestCompression)
+	tpl.SetDefault("g", "x")
+
+	m := map[string]interface{}{
+		"a": "1",
+		"b": map[string]interface{}{"c": "2"},
+		"e": "3",
+	}
+	if err := tpl.Validate(m); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	delete(m, "e")
+	m["b"] = map[string]interface{}{}
+	err := tpl.Validate(m)
+
+	var ve *ValidationError
+	if !errors.As(err, &ve) {
+		t.Fatalf("expected *ValidationError, got %#v", err)
+	}
+	if expect := []string{"b.c", "e"}; !reflect.DeepEqual(ve.Missing, expect) {
+		t.Fatalf("unexpected missing tags %q. Expected %q", ve.Missing, expect)
+	}
+	if ve.Unused != nil {
+		t.Fatalf("unexpected unused keys %q", ve.Unused)
+	}
+
+	m = map[string]interface{}{
+		"a":     "1",
+		"b":     map[string]interface{}{"c": "2"},
+		"e":     "3",
+		"items": nil,
+		"zz":    "4",
+		"yy":    "5",
+	}
+	if err := tpl.Validate(m); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	err = tpl.ValidateExact(m)
+	if !errors.As(err, &ve) {
+		t.Fatalf("expected *ValidationError, got %#v", err)
+	}
+	if ve.Missing != nil {
+		t.Fatalf("unexpected missing tags %q", ve.Missing)
+	}
+	if expect := []string{"yy", "zz"}; !reflect.DeepEqual(ve.Unused, expect) {
+		t.Fatalf("unexpected unused keys %q. Expected %q", ve.Unused, expect)
+	}
+
+	expect := "gziptemplate: invalid substitution map: unused keys yy, zz"
+	if err.Error() != expect {
+		t.Fatalf("unexpected error %q. Expected %q", err, expect)
+	}
+}
@@ -0,0 +1,117 @@
+package gziptemplate
+
+import (
+	"fmt"
+	"io"
+
+	"go.tmthrgd.dev/gzipbuilder"
+)
+
+// Value is a precompressed substitution value. It may be used as a value in
+// the map passed to Execute*.
+//
+// Where possible a Value is spliced into the output as is, without any
+// compression work, in the same way as the static segments of a template.
+// This suits values drawn from a small set of frequently used strings.
+// Values that are escaped or otherwise transformed are written
+// uncompressed.
+//
+// A Value may be shared by concurrently executing templates.
+type Value struct {
+	b []byte
+	d *gzipbuilder.PrecompressedData
+}
+
+// NewValue returns a Value holding b compressed at level. b must not be
+// modified afterwards.
+//
+// NewValue panics if level is not a valid compression level.
+func NewValue(b []byte, level int) *Value {
+	if level < HuffmanOnly || level > BestCompression {
+		panic(fmt.Sprintf("gziptemplate: invalid compression level: %d", level))
+	}
+
+	pw := gzipbuilder.NewPrecompressedWriter(level)
+	pw.Write(b)
+
+	d, err := pw.Data()
+	if err != nil {
+		panic(fmt.Sprintf("gziptemplate: failed to compress value: %s", err))
+	}
+
+	return &Value{b, d}
+}
+
+// Bytes returns the uncompressed value. It must not be modified.
+func (v *Value) Bytes() []byte {
+	return v.b
+}
+
+// writeTo writes v to w, the writer passed to a TagFunc.
+func (v *Value) writeTo(w io.Writer) error {
+	if sw, ok := w.(*spliceWriter); ok && len(v.b) != 0 {
+		return sw.s.splice(v.d, v.b)
+	}
+
+	_, err := w.Write(v.b)
+	return err
+}
+
+// RegisterValues precompresses values, the likely values for tag, so that
+// they are spliced into the output without compression work, as with
+// NewValue. This suits tags with a small set of values, such as A/B variants
+// or enumerations.
+//
+// The keys of values are matched against the string or []byte given for tag
+// when the template is executed, which is substituted with the
+// corresponding bytes. Other values are substituted as usual. Calling
+// RegisterValues again for tag replaces the values registered for it; a nil
+// values removes them. RegisterValues must not be called concurrently with
+// the Execute* methods.
+func (t *Template) RegisterValues(tag string, values map[string][]byte) {
+	if values == nil {
+		t.registerValues(tag, nil)
+		return
+	}
+
+	vs := make(map[string]*Value, len(values))
+	for k, b := range values {
+		vs[k] = NewValue(b, t.level)
+	}
+	t.registerValues(tag, vs)
+}
+
+// registerValues sets the values registered for tag.
+func (t *Template) registerValues(tag string, values map[string]*Value) {
+	if values == nil {
+		delete(t.registered, tag)
+		return
+	}
+
+	if t.registered == nil {
+		t.registered = make(map[string]map[string]*Value)
+	}
+	t.registered[tag] = values
+}
+
+// registeredValue returns the value registered for tag that matches v, or v
+// itself if there is none.
+func (t *Template) registeredValue(tag string, v interface{}) interface{} {
+	values := t.registered[tag]
+	if values == nil {
+		return v
+	}
+
+	var rv *Value
+	switch value := v.(type) {
+	case string:
+		rv = values[value]
+	case []byte:
+		rv = values[string(value)]
+	}
+	if rv == nil {
+		return v
+	}
+
+	return rv
+}
@@ -0,0 +1,78 @@
+package gziptemplate
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestValue(t *testing.T) {
+	v := NewValue([]byte("<b>hot</b>"), BestCompression)
+
+	tpl := New("[v]|[v]|[#if v]yes[#end]|[esc]", "[", "]", BestSpeed, WithEscaping(map[string]Escaping{
+		"esc": EscapeHTML,
+	}))
+	m := map[string]interface{}{"v": v, "esc": v}
+
+	const expect = "<b>hot</b>|<b>hot</b>|yes|&lt;b&gt;hot&lt;/b&gt;"
+
+	s := decompressBytes(t, tpl.ExecuteBytes(m))
+	if string(s) != expect {
+		t.Fatalf("unexpected template value %q. Expected %q", s, expect)
+	}
+
+	var gz, plain bytes.Buffer
+	if err := tpl.ExecuteDual(&gz, &plain, m); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if s := decompressBytes(t, gz.Bytes()); string(s) != expect {
+		t.Fatalf("unexpected template value %q. Expected %q", s, expect)
+	}
+	if plain.String() != expect {
+		t.Fatalf("unexpected identity output %q. Expected %q", plain.String(), expect)
+	}
+
+	expectPanic(t, func() {
+		NewValue(nil, BestCompression+1)
+	})
+}
+
+func TestRegisterValues(t *testing.T) {
+	tpl := New("<div class=[variant]>[#range items][variant][#end]</div>", "[", "]", BestCompression)
+	tpl.RegisterValues("variant", map[string][]byte{
+		"a": []byte("variant-a"),
+		"b": []byte("variant-b"),
+	})
+
+	for _, test := range []struct {
+		variant interface{}
+		result  string
+	}{
+		{"a", "<div class=variant-a>variant-b</div>"},
+		{[]byte("b"), "<div class=variant-b>variant-b</div>"},
+		{"c", "<div class=c>variant-b</div>"},
+	} {
+		m := map[string]interface{}{
+			"variant": test.variant,
+			"items":   []map[string]interface{}{{"variant": "b"}},
+		}
+
+		s := decompressBytes(t, tpl.ExecuteBytes(m))
+		if string(s) != test.result {
+			t.Fatalf("unexpected template value %q. Expected %q", s, test.result)
+		}
+
+		nt, err := tpl.WithLevel(BestSpeed)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if s := decompressBytes(t, nt.ExecuteBytes(m)); string(s) != test.result {
+			t.Fatalf("unexpected template value %q. Expected %q", s, test.result)
+		}
+	}
+
+	tpl.RegisterValues("variant", nil)
+	s := decompressBytes(t, tpl.ExecuteBytes(map[string]interface{}{"variant": "a"}))
+	if string(s) != "<div class=a></div>" {
+		t.Fatalf("unexpected template value %q. Expected %q", s, "<div class=a></div>")
+	}
+}